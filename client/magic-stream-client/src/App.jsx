@@ -3,6 +3,9 @@ import Home from "./components/home/Home";
 import Header from "./components/header/Header";
 import Register from "./components/register/Register";
 import Login from "../src/components/login/Login";
+import LoginMFA from "./components/login/LoginMFA";
+import ResetPassword from "./components/login/ResetPassword";
+import VerifyEmail from "./components/login/VerifyEmail";
 import { Route, Routes, useNavigate } from "react-router-dom";
 import Layout from "./components/Layout";
 import RequireAuth from "./components/RequiredAuth";
@@ -42,6 +45,9 @@ function App() {
         />
         <Route path="/register" element={<Register />} />
         <Route path="/login" element={<Login />} />
+        <Route path="/login/mfa" element={<LoginMFA />} />
+        <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/verify-email" element={<VerifyEmail />} />
         <Route element={<RequireAuth />}>
           <Route path="/recommended" element={<Recommended />} />
           <Route path="/review/:imdb_id" element={<Review />} />
@@ -1,10 +1,19 @@
 import axios from "axios";
+import { addTraceparent } from "./traceContext";
 const apiUrl = import.meta.env.VITE_API_BASE_URL;
 
-export default axios.create({
+const axiosClient = axios.create({
     baseURL: apiUrl,
     headers: {
         "Content-Type": "application/json",
     },
     withCredentials: true,
-})
\ No newline at end of file
+    // echo the csrf_token cookie back for the server's double-submit check
+    xsrfCookieName: "csrf_token",
+    xsrfHeaderName: "X-CSRF-Token",
+    withXSRFToken: true,
+})
+
+axiosClient.interceptors.request.use(addTraceparent);
+
+export default axiosClient;
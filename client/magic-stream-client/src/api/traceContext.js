@@ -0,0 +1,17 @@
+// W3C trace context for API calls. Each request starts a new trace, so the
+// server's spans for a call can be found from the traceparent header shown
+// in the browser's network panel.
+// https://www.w3.org/TR/trace-context/#traceparent-header
+
+const randomHex = (bytes) =>
+  Array.from(crypto.getRandomValues(new Uint8Array(bytes)), (b) =>
+    b.toString(16).padStart(2, "0")
+  ).join("");
+
+export const addTraceparent = (config) => {
+  if (!config.headers.traceparent) {
+    // version 00, 16-byte trace ID, 8-byte parent ID, sampled
+    config.headers.traceparent = `00-${randomHex(16)}-${randomHex(8)}-01`;
+  }
+  return config;
+};
@@ -31,6 +31,12 @@ const Login = () => {
         setError(response.data.error);
         return;
       }
+      if (response.data.mfa_required) {
+        navigate("/login/mfa", {
+          state: { mfaToken: response.data.mfa_token, from },
+        });
+        return;
+      }
       setAuth(response.data);
       navigate(from, { replace: true });
     } catch (err) {
@@ -0,0 +1,116 @@
+import { useState } from "react";
+import Container from "react-bootstrap/Container";
+import Button from "react-bootstrap/Button";
+import Form from "react-bootstrap/Form";
+import axiosClient from "../../api/axiosConfig";
+import { useNavigate, Link, useLocation } from "react-router-dom";
+import useAuth from "../../hook/useAuth";
+
+// Second login step for accounts with two-factor authentication. After a
+// password login the challenge token arrives in the router state; after an
+// external login the server keeps it in a cookie, so none is sent.
+const LoginMFA = () => {
+  const { setAuth } = useAuth();
+  const [code, setCode] = useState("");
+  const [useRecoveryCode, setUseRecoveryCode] = useState(false);
+
+  const [error, setError] = useState(null);
+  const [loading, setLoading] = useState(false);
+  const location = useLocation();
+  const navigate = useNavigate();
+
+  const mfaToken = location.state?.mfaToken;
+  const from = location.state?.from || "/";
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setLoading(true);
+    setError(null);
+
+    const payload = useRecoveryCode ? { recovery_code: code } : { code };
+    if (mfaToken) {
+      payload.mfa_token = mfaToken;
+    }
+    try {
+      const response = await axiosClient.post("/login/mfa", payload);
+      setAuth(response.data);
+      navigate(from, { replace: true });
+    } catch (err) {
+      console.error(err);
+      setError(err.response?.data?.error || "Invalid code");
+    } finally {
+      setLoading(false);
+    }
+  };
+  return (
+    <Container className="login-container d-flex align-items-center justify-content-center min-vh-100">
+      <div
+        className="login-card shadow p-4 rounded bg-white"
+        style={{ maxWidth: 400, width: "100%" }}
+      >
+        <div className="text-center mb-4">
+          <h2 className="fw-bold">Two-Factor Authentication</h2>
+          <p className="text-muted">
+            {useRecoveryCode
+              ? "Enter one of your recovery codes."
+              : "Enter the code from your authenticator app."}
+          </p>
+        </div>
+        {error && <div className="alert alert-danger py-2">{error}</div>}
+        <Form onSubmit={handleSubmit}>
+          <Form.Group controlId="formMfaCode" className="mb-3">
+            <Form.Label>{useRecoveryCode ? "Recovery code" : "Code"}</Form.Label>
+            <Form.Control
+              type="text"
+              inputMode={useRecoveryCode ? "text" : "numeric"}
+              autoComplete="one-time-code"
+              value={code}
+              onChange={(e) => setCode(e.target.value.trim())}
+              required
+              autoFocus
+            />
+          </Form.Group>
+
+          <Button
+            variant="primary"
+            type="submit"
+            className="w-100 mb-2"
+            disabled={loading}
+            style={{ fontWeight: 600, letterSpacing: 1 }}
+          >
+            {loading ? (
+              <>
+                <span
+                  className="spinner-border spinner-border-sm me-2"
+                  role="status"
+                  aria-hidden="true"
+                ></span>
+                Verifying...
+              </>
+            ) : (
+              "Verify"
+            )}
+          </Button>
+        </Form>
+        <div className="text-center mt-3">
+          <Button
+            variant="link"
+            className="fw-semibold p-0"
+            onClick={() => {
+              setUseRecoveryCode(!useRecoveryCode);
+              setCode("");
+            }}
+          >
+            {useRecoveryCode ? "Use an authenticator code" : "Use a recovery code"}
+          </Button>
+        </div>
+        <div className="text-center mt-2">
+          <Link to="/login" className="fw-semibold">
+            Back to login
+          </Link>
+        </div>
+      </div>
+    </Container>
+  );
+};
+export default LoginMFA;
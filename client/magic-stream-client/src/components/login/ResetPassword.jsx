@@ -0,0 +1,103 @@
+import { useState } from "react";
+import Container from "react-bootstrap/Container";
+import Button from "react-bootstrap/Button";
+import Form from "react-bootstrap/Form";
+import axiosClient from "../../api/axiosConfig";
+import { useNavigate, Link, useSearchParams } from "react-router-dom";
+
+// Landing page for the link in the password reset email.
+const ResetPassword = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+  const [password, setPassword] = useState("");
+  const [confirmPassword, setConfirmPassword] = useState("");
+
+  const [error, setError] = useState(null);
+  const [loading, setLoading] = useState(false);
+  const navigate = useNavigate();
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    setError(null);
+
+    if (password !== confirmPassword) {
+      setError("Passwords do not match.");
+      return;
+    }
+
+    setLoading(true);
+
+    try {
+      await axiosClient.post("/password/reset", { token, password });
+      navigate("/login", { replace: true });
+    } catch (err) {
+      console.error(err);
+      setError(err.response?.data?.error || "Could not reset password");
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  if (!token) {
+    return (
+      <Container className="d-flex align-items-center justify-content-center min-vh-100">
+        <div className="alert alert-danger">
+          This reset link is incomplete. Please use the link from your email.
+        </div>
+      </Container>
+    );
+  }
+  return (
+    <Container className="login-container d-flex align-items-center justify-content-center min-vh-100">
+      <div
+        className="login-card shadow p-4 rounded bg-white"
+        style={{ maxWidth: 400, width: "100%" }}
+      >
+        <div className="text-center mb-4">
+          <h2 className="fw-bold">Choose a New Password</h2>
+        </div>
+        {error && <div className="alert alert-danger py-2">{error}</div>}
+        <Form onSubmit={handleSubmit}>
+          <Form.Group controlId="formNewPassword" className="mb-3">
+            <Form.Label>New password</Form.Label>
+            <Form.Control
+              type="password"
+              autoComplete="new-password"
+              value={password}
+              onChange={(e) => setPassword(e.target.value)}
+              required
+              autoFocus
+            />
+          </Form.Group>
+
+          <Form.Group controlId="formConfirmPassword" className="mb-3">
+            <Form.Label>Confirm password</Form.Label>
+            <Form.Control
+              type="password"
+              autoComplete="new-password"
+              value={confirmPassword}
+              onChange={(e) => setConfirmPassword(e.target.value)}
+              required
+            />
+          </Form.Group>
+
+          <Button
+            variant="primary"
+            type="submit"
+            className="w-100 mb-2"
+            disabled={loading}
+            style={{ fontWeight: 600, letterSpacing: 1 }}
+          >
+            {loading ? "Saving..." : "Reset password"}
+          </Button>
+        </Form>
+        <div className="text-center mt-3">
+          <Link to="/login" className="fw-semibold">
+            Back to login
+          </Link>
+        </div>
+      </div>
+    </Container>
+  );
+};
+export default ResetPassword;
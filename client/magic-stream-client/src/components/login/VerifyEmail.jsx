@@ -0,0 +1,50 @@
+import { useEffect, useState } from "react";
+import Container from "react-bootstrap/Container";
+import axiosClient from "../../api/axiosConfig";
+import { Link, useSearchParams } from "react-router-dom";
+import Spinner from "../spinner/Spinner";
+
+// Landing page for the link in the verification email. It confirms the
+// token with the API as soon as it opens.
+const VerifyEmail = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+  const [message, setMessage] = useState(null);
+  const [error, setError] = useState(null);
+
+  useEffect(() => {
+    if (!token) {
+      setError("This verification link is incomplete. Please use the link from your email.");
+      return;
+    }
+    const verify = async () => {
+      try {
+        const response = await axiosClient.get("/verify-email", {
+          params: { token },
+        });
+        setMessage(response.data.message);
+      } catch (err) {
+        console.error(err);
+        setError(err.response?.data?.error || "Could not verify your email address");
+      }
+    };
+
+    verify();
+  }, [token]);
+
+  return (
+    <Container className="d-flex align-items-center justify-content-center min-vh-100">
+      <div className="text-center">
+        {!message && !error && <Spinner />}
+        {message && <div className="alert alert-success">{message}</div>}
+        {error && <div className="alert alert-danger">{error}</div>}
+        {(message || error) && (
+          <Link to="/login" className="fw-semibold">
+            Go to login
+          </Link>
+        )}
+      </div>
+    </Container>
+  );
+};
+export default VerifyEmail;
@@ -2,6 +2,7 @@ import {useEffect, useRef} from 'react';
 import axios from 'axios';
 
 import useAuth from './useAuth';
+import { addTraceparent } from '../api/traceContext';
 
 const apiUrl = import.meta.env.VITE_API_BASE_URL;
 
@@ -10,8 +11,13 @@ const useAxiosPrivate = () =>{
     const axiosAuth = axios.create({
         baseURL: apiUrl,
         withCredentials: true, // important for HTTP-only cookies
+        xsrfCookieName: 'csrf_token', // echoed back for the server's CSRF check
+        xsrfHeaderName: 'X-CSRF-Token',
+        withXSRFToken: true,
     });
 
+    axiosAuth.interceptors.request.use(addTraceparent);
+
     const {auth,setAuth} = useAuth();
 
     let isRefreshing = useRef(false);
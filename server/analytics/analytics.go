@@ -0,0 +1,346 @@
+// Package analytics builds the admin reports on catalogue and user activity.
+//
+// Every report is a table: Columns names the fields of each row, in the
+// order they are written to CSV. Time series have one row per period of
+// the requested range, including periods where nothing happened.
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Intervals a time series can be grouped by.
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	// MaxRangeDays bounds a report's range so time series stay small.
+	MaxRangeDays = 731
+)
+
+var ErrInvalidRange = errors.New("invalid date range")
+
+// Range is the days a report covers. From and To are midnight UTC and both
+// days are included.
+type Range struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+// end is the first instant after the range.
+func (r Range) end() time.Time {
+	return r.To.AddDate(0, 0, 1)
+}
+
+// ParseRange reads from and to as YYYY-MM-DD. Either may be empty: to
+// defaults to today and from to 29 days before to. interval defaults to day.
+func ParseRange(from, to, interval string) (Range, error) {
+	r := Range{To: time.Now().UTC().Truncate(24 * time.Hour), Interval: interval}
+	if r.Interval == "" {
+		r.Interval = IntervalDay
+	}
+	if r.Interval != IntervalDay && r.Interval != IntervalWeek && r.Interval != IntervalMonth {
+		return Range{}, fmt.Errorf("%w: interval must be day, week or month", ErrInvalidRange)
+	}
+	if to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return Range{}, fmt.Errorf("%w: to must be a YYYY-MM-DD date", ErrInvalidRange)
+		}
+		r.To = t
+	}
+	r.From = r.To.AddDate(0, 0, -29)
+	if from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return Range{}, fmt.Errorf("%w: from must be a YYYY-MM-DD date", ErrInvalidRange)
+		}
+		r.From = t
+	}
+	if r.From.After(r.To) {
+		return Range{}, fmt.Errorf("%w: from must not be after to", ErrInvalidRange)
+	}
+	if r.To.Sub(r.From) > MaxRangeDays*24*time.Hour {
+		return Range{}, fmt.Errorf("%w: at most %d days can be reported at once", ErrInvalidRange, MaxRangeDays)
+	}
+	return r, nil
+}
+
+// periodStart returns the start of the interval t falls in. Weeks start on
+// Monday, as with $dateTrunc below.
+func periodStart(t time.Time, interval string) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	switch interval {
+	case IntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case IntervalMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day
+}
+
+func nextPeriod(t time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// periodExpr groups field into r's periods in an aggregation.
+func periodExpr(field string, r Range) bson.M {
+	return bson.M{"$dateTrunc": bson.M{"date": "$" + field, "unit": r.Interval, "startOfWeek": "monday", "timezone": "UTC"}}
+}
+
+// Report is one table of results.
+type Report struct {
+	Name    string   `json:"report"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Columns []string `json:"columns"`
+	Rows    []bson.M `json:"rows"`
+}
+
+func newReport(name string, r *Range, columns ...string) *Report {
+	report := &Report{Name: name, Columns: columns, Rows: []bson.M{}}
+	if r != nil {
+		report.From = r.From.Format(dateLayout)
+		report.To = r.To.Format(dateLayout)
+	}
+	return report
+}
+
+// WriteCSV writes the report with a header row. Periods are written as
+// dates.
+func (rep *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(rep.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(rep.Columns))
+	for _, row := range rep.Rows {
+		for i, col := range rep.Columns {
+			record[i] = formatCell(row[col])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatCell(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		return v.Format(dateLayout)
+	case bson.DateTime:
+		return v.Time().UTC().Format(dateLayout)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// aggregate runs pipeline on collection and decodes every result.
+func aggregate(ctx context.Context, collection *mongo.Collection, pipeline bson.A) ([]bson.M, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	rows := []bson.M{}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// series turns rows keyed by a "period" date into one row per period of r.
+// Periods with no row get zero for every column after the first.
+func series(rows []bson.M, r Range, columns []string) []bson.M {
+	byPeriod := map[time.Time]bson.M{}
+	for _, row := range rows {
+		if dt, ok := row["period"].(bson.DateTime); ok {
+			byPeriod[dt.Time().UTC()] = row
+		}
+	}
+	filled := []bson.M{}
+	for p := periodStart(r.From, r.Interval); !p.After(r.To); p = nextPeriod(p, r.Interval) {
+		row := bson.M{"period": p}
+		for _, col := range columns[1:] {
+			row[col] = 0
+			if found, ok := byPeriod[p]; ok && found[col] != nil {
+				row[col] = found[col]
+			}
+		}
+		filled = append(filled, row)
+	}
+	return filled
+}
+
+// Registrations counts the users who signed up in each period.
+func Registrations(ctx context.Context, r Range) (*Report, error) {
+	report := newReport("registrations", &r, "period", "registrations")
+	rows, err := aggregate(ctx, database.Users, bson.A{
+		bson.M{"$match": bson.M{"created_at": bson.M{"$gte": r.From, "$lt": r.end()}}},
+		bson.M{"$group": bson.M{"_id": periodExpr("created_at", r), "registrations": bson.M{"$sum": 1}}},
+		bson.M{"$project": bson.M{"_id": 0, "period": "$_id", "registrations": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = series(rows, r, report.Columns)
+	return report, nil
+}
+
+// ActiveUsers counts the distinct users who made an authenticated request
+// in each period.
+func ActiveUsers(ctx context.Context, r Range) (*Report, error) {
+	report := newReport("active_users", &r, "period", "active_users")
+	rows, err := aggregate(ctx, database.UserActivity, bson.A{
+		bson.M{"$match": bson.M{"day": bson.M{"$gte": r.From, "$lt": r.end()}}},
+		bson.M{"$group": bson.M{"_id": periodExpr("day", r), "users": bson.M{"$addToSet": "$user_id"}}},
+		bson.M{"$project": bson.M{"_id": 0, "period": "$_id", "active_users": bson.M{"$size": "$users"}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = series(rows, r, report.Columns)
+	return report, nil
+}
+
+// MostWatched lists the movies played most often in the range.
+func MostWatched(ctx context.Context, r Range, limit int) (*Report, error) {
+	report := newReport("most_watched", &r, "imdb_id", "title", "plays", "completions", "views")
+	rows, err := aggregate(ctx, database.MovieStatsDaily, bson.A{
+		bson.M{"$match": bson.M{"start": bson.M{"$gte": r.From, "$lt": r.end()}}},
+		bson.M{"$group": bson.M{
+			"_id":         "$imdb_id",
+			"plays":       bson.M{"$sum": "$plays"},
+			"completions": bson.M{"$sum": "$completions"},
+			"views":       bson.M{"$sum": "$views"},
+		}},
+		bson.M{"$match": bson.M{"plays": bson.M{"$gt": 0}}},
+		bson.M{"$sort": bson.D{{Key: "plays", Value: -1}, {Key: "completions", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+		bson.M{"$lookup": bson.M{"from": database.Movies.Name(), "localField": "_id", "foreignField": "imdb_id", "as": "movie"}},
+		bson.M{"$project": bson.M{
+			"_id":         0,
+			"imdb_id":     "$_id",
+			"title":       bson.M{"$first": "$movie.title"},
+			"plays":       1,
+			"completions": 1,
+			"views":       1,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = rows
+	return report, nil
+}
+
+// FavouriteGenres counts how many users have picked each genre. It describes
+// the users as they are now, so it takes no range.
+func FavouriteGenres(ctx context.Context) (*Report, error) {
+	report := newReport("favourite_genres", nil, "genre_name", "users")
+	rows, err := aggregate(ctx, database.Users, bson.A{
+		bson.M{"$unwind": "$favourite_genres"},
+		bson.M{"$group": bson.M{"_id": "$favourite_genres.genre_name", "users": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "users", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$project": bson.M{"_id": 0, "genre_name": "$_id", "users": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = rows
+	return report, nil
+}
+
+// RankingDistribution counts the movies at each ranking. It describes the
+// catalogue as it is now, so it takes no range.
+func RankingDistribution(ctx context.Context) (*Report, error) {
+	report := newReport("rankings", nil, "ranking_value", "ranking_name", "movies")
+	rows, err := aggregate(ctx, database.Movies, bson.A{
+		bson.M{"$group": bson.M{
+			"_id":    bson.M{"value": "$ranking.ranking_value", "name": "$ranking.ranking_name"},
+			"movies": bson.M{"$sum": 1},
+		}},
+		bson.M{"$sort": bson.D{{Key: "_id.value", Value: 1}}},
+		bson.M{"$project": bson.M{"_id": 0, "ranking_value": "$_id.value", "ranking_name": "$_id.name", "movies": 1}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = rows
+	return report, nil
+}
+
+// LLMFailures reports how many review classifications failed in each
+// period, either because the call errored or because the answer was not a
+// ranking name.
+func LLMFailures(ctx context.Context, r Range) (*Report, error) {
+	report := newReport("llm_failures", &r, "period", "calls", "failures", "unmatched", "failure_rate")
+	rows, err := aggregate(ctx, database.LLMStatsDaily, bson.A{
+		bson.M{"$match": bson.M{"start": bson.M{"$gte": r.From, "$lt": r.end()}}},
+		bson.M{"$group": bson.M{
+			"_id":       periodExpr("start", r),
+			"success":   bson.M{"$sum": "$success"},
+			"failures":  bson.M{"$sum": "$failure"},
+			"unmatched": bson.M{"$sum": "$unmatched"},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":       0,
+			"period":    "$_id",
+			"calls":     bson.M{"$add": bson.A{"$success", "$failures", "$unmatched"}},
+			"failures":  1,
+			"unmatched": 1,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Rows = series(rows, r, report.Columns)
+	for _, row := range report.Rows {
+		calls, failed := toFloat(row["calls"]), toFloat(row["failures"])+toFloat(row["unmatched"])
+		row["failure_rate"] = 0.0
+		if calls > 0 {
+			row["failure_rate"] = failed / calls
+		}
+	}
+	return report, nil
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
@@ -0,0 +1,418 @@
+// Package backup exports catalogue collections as JSON Lines or CSV and
+// imports them back, validating every record with the model validators.
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+var (
+	ErrUnknownCollection = errors.New("unknown collection")
+	ErrUnknownFormat     = errors.New("format must be jsonl or csv")
+	ErrNotImportable     = errors.New("collection cannot be imported")
+	ErrMalformedInput    = errors.New("malformed input")
+)
+
+// column is one CSV column. Raw columns hold the field's JSON encoding, which
+// keeps numbers, booleans and nested documents unambiguous.
+type column struct {
+	name string
+	raw  bool
+}
+
+// ExportedUser is the user record written to exports. Password hashes,
+// session tokens and second factor secrets are deliberately absent.
+type ExportedUser struct {
+	UserID          string         `bson:"user_id" json:"user_id"`
+	FirstName       string         `bson:"first_name" json:"first_name"`
+	LastName        string         `bson:"last_name" json:"last_name"`
+	Email           string         `bson:"email" json:"email"`
+	Role            string         `bson:"role" json:"role"`
+	FavouriteGenres []models.Genre `bson:"favourite_genres" json:"favourite_genres"`
+	EmailVerified   bool           `bson:"email_verified" json:"email_verified"`
+	MFAEnabled      bool           `bson:"mfa_enabled" json:"mfa_enabled"`
+	CreatedAt       time.Time      `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+type spec struct {
+	collection func() *mongo.Collection
+	key        string
+	columns    []column
+	newRecord  func() any
+	importable bool
+	// link runs before an imported record is written; unlink receives the
+	// record it replaced.
+	link   func(ctx context.Context, record any) error
+	unlink func(ctx context.Context, previous any) error
+}
+
+var specs = map[string]spec{
+	"movies": {
+		collection: func() *mongo.Collection { return database.Movies },
+		key:        "imdb_id",
+		columns: []column{
+			{name: "imdb_id"}, {name: "title"}, {name: "poster_path"}, {name: "youtube_id"},
+			{name: "genre", raw: true}, {name: "admin_review"}, {name: "ranking", raw: true},
+			{name: "release_date"}, {name: "runtime_minutes", raw: true}, {name: "synopsis"},
+			{name: "cast", raw: true}, {name: "crew", raw: true}, {name: "spoken_languages", raw: true},
+			{name: "country"}, {name: "certification"},
+		},
+		newRecord:  func() any { return &models.Movie{} },
+		importable: true,
+		link: func(ctx context.Context, record any) error {
+			// Person IDs in an export belong to the source database, so
+			// credits are matched again by name.
+			movie := record.(*models.Movie)
+			for i := range movie.Cast {
+				movie.Cast[i].PersonID = ""
+			}
+			for i := range movie.Crew {
+				movie.Crew[i].PersonID = ""
+			}
+			return database.LinkPeople(ctx, movie)
+		},
+		unlink: func(ctx context.Context, previous any) error {
+			return database.PrunePeople(ctx, database.CreditedPeople(previous.(*models.Movie)))
+		},
+	},
+	"genres": {
+		collection: func() *mongo.Collection { return database.Genres },
+		key:        "genre_id",
+		columns:    []column{{name: "genre_id", raw: true}, {name: "genre_name"}},
+		newRecord:  func() any { return &models.Genre{} },
+		importable: true,
+	},
+	"rankings": {
+		collection: func() *mongo.Collection { return database.Rankings },
+		key:        "ranking_value",
+		columns:    []column{{name: "ranking_value", raw: true}, {name: "ranking_name"}},
+		newRecord:  func() any { return &models.Ranking{} },
+		importable: true,
+	},
+	// Users can be exported for reporting but not restored, since the export
+	// carries no password hashes.
+	"users": {
+		collection: func() *mongo.Collection { return database.Users },
+		key:        "email",
+		columns: []column{
+			{name: "user_id"}, {name: "first_name"}, {name: "last_name"}, {name: "email"}, {name: "role"},
+			{name: "favourite_genres", raw: true}, {name: "email_verified", raw: true}, {name: "mfa_enabled", raw: true},
+			{name: "created_at"}, {name: "updated_at"},
+		},
+		newRecord: func() any { return &ExportedUser{} },
+	},
+}
+
+var validate = validator.New()
+
+func lookup(name, format string) (spec, error) {
+	s, ok := specs[name]
+	if !ok {
+		return spec{}, fmt.Errorf("%w %q", ErrUnknownCollection, name)
+	}
+	if format != FormatJSONL && format != FormatCSV {
+		return spec{}, ErrUnknownFormat
+	}
+	return s, nil
+}
+
+// Export writes every document in the named collection to w, ordered by its
+// natural key.
+func Export(ctx context.Context, w io.Writer, name, format string) error {
+	s, err := lookup(name, format)
+	if err != nil {
+		return err
+	}
+	cursor, err := s.collection().Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: s.key, Value: 1}}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var csvWriter *csv.Writer
+	if format == FormatCSV {
+		csvWriter = csv.NewWriter(w)
+		header := make([]string, len(s.columns))
+		for i, col := range s.columns {
+			header[i] = col.name
+		}
+		if err := csvWriter.Write(header); err != nil {
+			return err
+		}
+	}
+	encoder := json.NewEncoder(w)
+	for cursor.Next(ctx) {
+		record := s.newRecord()
+		if err := cursor.Decode(record); err != nil {
+			return err
+		}
+		if csvWriter == nil {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+			continue
+		}
+		row, err := toRow(s.columns, record)
+		if err != nil {
+			return err
+		}
+		if err := csvWriter.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return csvWriter.Error()
+	}
+	return nil
+}
+
+func toRow(columns []column, record any) ([]string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		value, ok := fields[col.name]
+		if !ok || string(value) == "null" {
+			continue
+		}
+		if col.raw {
+			row[i] = string(value)
+		} else if err := json.Unmarshal(value, &row[i]); err != nil {
+			return nil, fmt.Errorf("column %s: %w", col.name, err)
+		}
+	}
+	return row, nil
+}
+
+// ImportOptions controls how existing records are treated.
+type ImportOptions struct {
+	// Overwrite replaces records whose natural key already exists instead of
+	// reporting them as conflicts.
+	Overwrite bool
+	// DryRun validates and checks for conflicts without writing anything.
+	DryRun bool
+}
+
+// RecordError points at a record that was not imported. Line is the line of
+// a JSON Lines file or the row of a CSV file, counting the header.
+type RecordError struct {
+	Line  int    `json:"line"`
+	Key   any    `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+type ImportReport struct {
+	Collection string        `json:"collection"`
+	Inserted   int           `json:"inserted"`
+	Replaced   int           `json:"replaced"`
+	Conflicts  []RecordError `json:"conflicts"`
+	Invalid    []RecordError `json:"invalid"`
+	DryRun     bool          `json:"dry_run"`
+}
+
+// Import reads records from r into the named collection. Invalid records and
+// conflicts are collected in the report and do not stop the import; only
+// unreadable input and database failures return an error.
+func Import(ctx context.Context, r io.Reader, name, format string, opts ImportOptions) (*ImportReport, error) {
+	s, err := lookup(name, format)
+	if err != nil {
+		return nil, err
+	}
+	if !s.importable {
+		return nil, fmt.Errorf("%w: %s", ErrNotImportable, name)
+	}
+	report := &ImportReport{Collection: name, Conflicts: []RecordError{}, Invalid: []RecordError{}, DryRun: opts.DryRun}
+	apply := func(line int, data []byte, parseErr error) error {
+		if parseErr != nil {
+			report.Invalid = append(report.Invalid, RecordError{Line: line, Error: parseErr.Error()})
+			return nil
+		}
+		return importRecord(ctx, s, report, opts, line, data)
+	}
+	if format == FormatJSONL {
+		err = readJSONL(r, apply)
+	} else {
+		err = readCSV(r, s.columns, apply)
+	}
+	if err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+func readJSONL(r io.Reader, apply func(int, []byte, error) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		if err := apply(line, append([]byte(nil), scanner.Bytes()...), nil); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: line %d: %w", ErrMalformedInput, line+1, err)
+	}
+	return nil
+}
+
+func readCSV(r io.Reader, columns []column, apply func(int, []byte, error) error) error {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%w: reading CSV header: %w", ErrMalformedInput, err)
+	}
+	raw := map[string]bool{}
+	for _, col := range columns {
+		raw[col.name] = col.raw
+	}
+	line := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		line++
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %w", ErrMalformedInput, line, err)
+		}
+		fields := map[string]json.RawMessage{}
+		for i, name := range header {
+			if i >= len(row) || row[i] == "" {
+				continue
+			}
+			if raw[name] {
+				fields[name] = json.RawMessage(row[i])
+				continue
+			}
+			fields[name], _ = json.Marshal(row[i])
+		}
+		// Marshalling fails when a raw column holds malformed JSON, which is
+		// reported against the record rather than aborting the import.
+		data, err := json.Marshal(fields)
+		if err := apply(line, data, err); err != nil {
+			return err
+		}
+	}
+}
+
+func importRecord(ctx context.Context, s spec, report *ImportReport, opts ImportOptions, line int, data []byte) error {
+	record := s.newRecord()
+	if err := json.Unmarshal(data, record); err != nil {
+		report.Invalid = append(report.Invalid, RecordError{Line: line, Error: err.Error()})
+		return nil
+	}
+	if err := validate.Struct(record); err != nil {
+		report.Invalid = append(report.Invalid, RecordError{Line: line, Error: err.Error()})
+		return nil
+	}
+	doc, err := toDocument(record)
+	if err != nil {
+		return err
+	}
+	var key any
+	for _, elem := range doc {
+		if elem.Key == s.key {
+			key = elem.Value
+		}
+	}
+	filter := bson.D{{Key: s.key, Value: key}}
+
+	exists, err := s.collection().CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return err
+	}
+	if exists > 0 && !opts.Overwrite {
+		report.Conflicts = append(report.Conflicts, RecordError{Line: line, Key: key, Error: s.key + " already exists"})
+		return nil
+	}
+	if opts.DryRun {
+		if exists > 0 {
+			report.Replaced++
+		} else {
+			report.Inserted++
+		}
+		return nil
+	}
+	if s.link != nil {
+		if err := s.link(ctx, record); err != nil {
+			return err
+		}
+		if doc, err = toDocument(record); err != nil {
+			return err
+		}
+	}
+	if exists > 0 {
+		previous := s.newRecord()
+		if err := s.collection().FindOneAndReplace(ctx, filter, doc).Decode(previous); err != nil {
+			return err
+		}
+		report.Replaced++
+		if s.unlink != nil {
+			return s.unlink(ctx, previous)
+		}
+		return nil
+	}
+	if _, err := s.collection().InsertOne(ctx, doc); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			report.Conflicts = append(report.Conflicts, RecordError{Line: line, Key: key, Error: s.key + " already exists"})
+			return nil
+		}
+		return err
+	}
+	report.Inserted++
+	return nil
+}
+
+// toDocument converts a record to the document that is written, dropping any
+// _id so restored records never collide with existing object IDs.
+func toDocument(record any) (bson.D, error) {
+	data, err := bson.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	out := doc[:0]
+	for _, elem := range doc {
+		if elem.Key != "_id" {
+			out = append(out, elem)
+		}
+	}
+	return out, nil
+}
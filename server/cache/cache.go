@@ -0,0 +1,52 @@
+// Package cache stores serialised responses for a short time.
+//
+// The in-process Memory cache is used by default. A shared cache such as
+// Redis can be plugged in by implementing Cache; until then each instance
+// keeps its own entries, so a write on one instance only invalidates that
+// instance's cache and the others catch up when their entries expire.
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Cache is safe for concurrent use. Errors are reported so they can be
+// logged, but callers treat a failing cache as a miss.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// DeletePrefix removes every entry whose key starts with prefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// FromEnv builds the cache selected by CACHE_BACKEND: "memory" (the
+// default) or "none". Each cache has its own budget so one kind of entry
+// cannot evict another: a memory cache is bounded by <prefix>_MAX_ENTRIES
+// and <prefix>_MAX_BYTES, falling back to the given defaults.
+func FromEnv(prefix string, maxEntries, maxBytes int) (Cache, error) {
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "", "memory":
+		if v, err := strconv.Atoi(os.Getenv(prefix + "_MAX_ENTRIES")); err == nil && v > 0 {
+			maxEntries = v
+		}
+		if v, err := strconv.Atoi(os.Getenv(prefix + "_MAX_BYTES")); err == nil && v > 0 {
+			maxBytes = v
+		}
+		return NewMemory(maxEntries, maxBytes), nil
+	case "none":
+		return None{}, nil
+	default:
+		return nil, fmt.Errorf("unknown CACHE_BACKEND %q", backend)
+	}
+}
+
+// None caches nothing.
+type None struct{}
+
+func (None) Get(context.Context, string) ([]byte, bool, error)        { return nil, false, nil }
+func (None) Set(context.Context, string, []byte, time.Duration) error { return nil }
+func (None) DeletePrefix(context.Context, string) error               { return nil }
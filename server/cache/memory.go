@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process LRU cache bounded by entry count and total value
+// size. Expired entries are dropped when they are next looked up or when
+// space is needed.
+type Memory struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	order      *list.List // front is most recently used
+	entries    map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewMemory(maxEntries, maxBytes int) *Memory {
+	return &Memory{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    map[string]*list.Element{},
+	}
+}
+
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	if time.Now().After(entry.expires) {
+		m.remove(el)
+		return nil, false, nil
+	}
+	m.order.MoveToFront(el)
+	return entry.value, true, nil
+}
+
+// Set stores value unless it alone is larger than the cache.
+func (m *Memory) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	if len(value) > m.maxBytes {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	entry := &memoryEntry{key: key, value: value, expires: time.Now().Add(ttl)}
+	m.entries[key] = m.order.PushFront(entry)
+	m.bytes += len(value)
+	m.evict()
+	return nil
+}
+
+func (m *Memory) DeletePrefix(_ context.Context, prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, el := range m.entries {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+// evict drops expired entries, then the least recently used, until the
+// cache is within its limits.
+func (m *Memory) evict() {
+	if len(m.entries) <= m.maxEntries && m.bytes <= m.maxBytes {
+		return
+	}
+	now := time.Now()
+	for el := m.order.Back(); el != nil; {
+		prev := el.Prev()
+		if now.After(el.Value.(*memoryEntry).expires) {
+			m.remove(el)
+		}
+		el = prev
+	}
+	for len(m.entries) > m.maxEntries || m.bytes > m.maxBytes {
+		m.remove(m.order.Back())
+	}
+}
+
+func (m *Memory) remove(el *list.Element) {
+	entry := m.order.Remove(el).(*memoryEntry)
+	delete(m.entries, entry.key)
+	m.bytes -= len(entry.value)
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/analytics"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultMostWatchedLimit = 20
+	maxMostWatchedLimit     = 100
+)
+
+type reportBuilder func(ctx context.Context, r analytics.Range, limit int) (*analytics.Report, error)
+
+var analyticsReports = map[string]reportBuilder{
+	"registrations": func(ctx context.Context, r analytics.Range, _ int) (*analytics.Report, error) {
+		return analytics.Registrations(ctx, r)
+	},
+	"active-users": func(ctx context.Context, r analytics.Range, _ int) (*analytics.Report, error) {
+		return analytics.ActiveUsers(ctx, r)
+	},
+	"most-watched": analytics.MostWatched,
+	"favourite-genres": func(ctx context.Context, _ analytics.Range, _ int) (*analytics.Report, error) {
+		return analytics.FavouriteGenres(ctx)
+	},
+	"rankings": func(ctx context.Context, _ analytics.Range, _ int) (*analytics.Report, error) {
+		return analytics.RankingDistribution(ctx)
+	},
+	"llm-failures": func(ctx context.Context, r analytics.Range, _ int) (*analytics.Report, error) {
+		return analytics.LLMFailures(ctx, r)
+	},
+}
+
+// GetAnalyticsReport returns one admin report as JSON, or as a CSV download
+// with format=csv. from and to (YYYY-MM-DD) pick the days covered, 30 days
+// up to today by default, and interval groups time series by day, week or
+// month.
+func GetAnalyticsReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can view analytics"})
+			return
+		}
+		name := c.Param("report")
+		build, ok := analyticsReports[name]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown report"})
+			return
+		}
+		r, err := analytics.ParseRange(c.Query("from"), c.Query("to"), c.Query("interval"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		limit := defaultMostWatchedLimit
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > maxMostWatchedLimit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+				return
+			}
+			limit = n
+		}
+		format := c.DefaultQuery("format", "json")
+		if format != "json" && format != "csv" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		report, err := build(ctx, r, limit)
+		if err != nil {
+			logging.FromContext(c, logger).Error("error building analytics report", "report", name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error building report"})
+			return
+		}
+		if format == "json" {
+			c.JSON(http.StatusOK, report)
+			return
+		}
+		filename := name
+		if report.From != "" {
+			filename += "-" + report.From + "-" + report.To
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		c.Status(http.StatusOK)
+		if err := report.WriteCSV(c.Writer); err != nil {
+			logging.FromContext(c, logger).Error("error writing analytics report", "report", name, "error", err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// CreateAPIKey returns the raw key exactly once; only its hash is stored.
+func CreateAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req models.CreateAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+		secret, _, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating API key"})
+			return
+		}
+		rawKey := utils.APIKeyPrefix + secret
+		key := models.APIKey{
+			KeyID:     bson.NewObjectID().Hex(),
+			UserID:    userId,
+			Name:      req.Name,
+			Prefix:    rawKey[:len(utils.APIKeyPrefix)+6],
+			KeyHash:   utils.HashOpaqueToken(rawKey),
+			Scopes:    req.Scopes,
+			CreatedAt: time.Now(),
+		}
+		if req.ExpiresInDays > 0 {
+			expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+			key.ExpiresAt = &expiresAt
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		if _, err := database.APIKeys.InsertOne(ctx, key); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing API key"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"api_key": rawKey, "key": key})
+	}
+}
+
+func ListAPIKeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+		cursor, err := database.APIKeys.Find(ctx, bson.M{"user_id": userId}, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching API keys"})
+			return
+		}
+		defer cursor.Close(ctx)
+		keys := []models.APIKey{}
+		if err := cursor.All(ctx, &keys); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding API keys"})
+			return
+		}
+		c.JSON(http.StatusOK, keys)
+	}
+}
+
+// RevokeAPIKey lets users revoke their own keys and admins revoke anyone's.
+func RevokeAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		filter := bson.M{"key_id": c.Param("key_id"), "revoked_at": bson.M{"$exists": false}}
+		if role != "admin" {
+			filter["user_id"] = userId
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		result, err := database.APIKeys.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": time.Now()}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error revoking API key"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+	}
+}
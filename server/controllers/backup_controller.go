@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/backup"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize bounds the request body accepted by ImportCollection.
+const maxImportSize = 32 << 20
+
+var exportContentTypes = map[string]string{
+	backup.FormatJSONL: "application/x-ndjson",
+	backup.FormatCSV:   "text/csv; charset=utf-8",
+}
+
+func isBackupRequestError(err error) bool {
+	return errors.Is(err, backup.ErrUnknownCollection) || errors.Is(err, backup.ErrUnknownFormat) || errors.Is(err, backup.ErrNotImportable)
+}
+
+// ExportCollection streams a collection as JSON Lines (the default) or CSV.
+func ExportCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can export data"})
+			return
+		}
+		name := c.Param("collection")
+		format := c.DefaultQuery("format", backup.FormatJSONL)
+		// Large collections take longer than the usual request timeout.
+		ctx, cancel := context.WithTimeout(c.Request.Context(), streamTimeout)
+		defer cancel()
+
+		c.Header("Content-Type", exportContentTypes[format])
+		c.Header("Trailer", streamErrorTrailer)
+		c.Header("Content-Disposition", `attachment; filename="`+name+"-"+time.Now().UTC().Format("20060102T150405Z")+"."+format+`"`)
+		err = backup.Export(ctx, c.Writer, name, format)
+		switch {
+		case err == nil:
+		case isBackupRequestError(err):
+			clearExportHeaders(c)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case !c.Writer.Written():
+			clearExportHeaders(c)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error exporting data"})
+		default:
+			// Part of the export has been sent, so the status can no longer change
+			// and the failure is reported in the trailer instead.
+			logging.FromContext(c, logger).Error("export interrupted", "collection", name, "error", err)
+			c.Writer.Header().Set(streamErrorTrailer, "Error exporting data")
+			c.Abort()
+		}
+	}
+}
+
+// clearExportHeaders removes the download headers so an error goes out as a
+// plain JSON response.
+func clearExportHeaders(c *gin.Context) {
+	for _, name := range []string{"Content-Type", "Content-Disposition", "Trailer"} {
+		c.Writer.Header().Del(name)
+	}
+}
+
+// ImportCollection loads a JSON Lines or CSV body into a collection. Records
+// whose natural key exists are reported as conflicts unless overwrite=true;
+// dry_run=true reports what would happen without writing.
+func ImportCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can import data"})
+			return
+		}
+		opts := backup.ImportOptions{
+			Overwrite: c.Query("overwrite") == "true",
+			DryRun:    c.Query("dry_run") == "true",
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+		report, err := backup.Import(ctx, body, c.Param("collection"), c.DefaultQuery("format", backup.FormatJSONL), opts)
+		// A failed import may still have written some records.
+		if !opts.DryRun {
+			invalidateCatalogue(c)
+		}
+		if err != nil {
+			if isBackupRequestError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import file is too large", "report": report})
+				return
+			}
+			if errors.Is(err, backup.ErrMalformedInput) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+				return
+			}
+			logging.FromContext(c, logger).Error("import failed", "collection", c.Param("collection"), "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error importing data", "report": report})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
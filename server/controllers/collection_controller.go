@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const defaultSmartCollectionLimit = 20
+
+var collectionOrder = options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}})
+
+// visibleNow matches collections whose schedule window, if any, includes now.
+func visibleNow() bson.M {
+	now := time.Now()
+	return bson.M{"$and": bson.A{
+		bson.M{"$or": bson.A{bson.M{"visible_from": bson.M{"$exists": false}}, bson.M{"visible_from": bson.M{"$lte": now}}}},
+		bson.M{"$or": bson.A{bson.M{"visible_until": bson.M{"$exists": false}}, bson.M{"visible_until": bson.M{"$gt": now}}}},
+	}}
+}
+
+// collectionMovies resolves the movies in a collection, in display order.
+// Smart collections list the best ranked matches first, then the newest.
+func collectionMovies(ctx context.Context, collection *models.CuratedCollection) ([]models.Movie, error) {
+	if collection.Kind == models.CollectionSmart {
+		limit := collection.Limit
+		if limit == 0 {
+			limit = defaultSmartCollectionLimit
+		}
+		var rules models.MovieQuery
+		if collection.Rules != nil {
+			rules = *collection.Rules
+		}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "ranking.ranking_value", Value: 1}, {Key: "release_date", Value: -1}}).
+			SetLimit(int64(limit))
+		return database.FindAll[models.Movie](ctx, database.Movies, database.MovieFilter(rules), opts)
+	}
+	return moviesInOrder(ctx, collection.ImdbIDs)
+}
+
+// missingMovies returns the IDs in imdbIds that are not in the catalogue.
+func missingMovies(ctx context.Context, imdbIds []string) ([]string, error) {
+	if len(imdbIds) == 0 {
+		return nil, nil
+	}
+	found, err := database.Movies.Distinct(ctx, "imdb_id", bson.M{"imdb_id": bson.M{"$in": imdbIds}}).Raw()
+	if err != nil {
+		return nil, err
+	}
+	values, err := found.Values()
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, v := range values {
+		known[v.StringValue()] = true
+	}
+	var missing []string
+	for _, id := range imdbIds {
+		if !known[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing, nil
+}
+
+// bindCollectionRequest validates a create or update request and writes the
+// error response itself when it is rejected.
+func bindCollectionRequest(c *gin.Context, ctx context.Context) (*models.CuratedCollectionRequest, bool) {
+	var req models.CuratedCollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection data"})
+		return nil, false
+	}
+	if err := validate.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if req.VisibleFrom != nil && req.VisibleUntil != nil && !req.VisibleUntil.After(*req.VisibleFrom) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visible_until must be after visible_from"})
+		return nil, false
+	}
+	if req.Kind == models.CollectionSmart {
+		req.ImdbIDs = nil
+	} else {
+		req.Rules = nil
+		missing, err := missingMovies(ctx, req.ImdbIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking movies"})
+			return nil, false
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some movies do not exist", "missing": missing})
+			return nil, false
+		}
+	}
+	if req.ImdbIDs == nil {
+		req.ImdbIDs = []string{}
+	}
+	return &req, true
+}
+
+// ListCollections returns the collections that are currently visible.
+func ListCollections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		collections, err := database.FindAll[models.CuratedCollection](ctx, database.CuratedCollections, visibleNow(), collectionOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching collections"})
+			return
+		}
+		c.JSON(http.StatusOK, collections)
+	}
+}
+
+// GetCollection returns a visible collection with its movies.
+func GetCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		filter := visibleNow()
+		filter["collection_id"] = c.Param("collection_id")
+		collection, err := database.FindOne[models.CuratedCollection](ctx, database.CuratedCollections, filter)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+			return
+		}
+		movies, err := collectionMovies(ctx, collection)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching collection movies"})
+			return
+		}
+		c.JSON(http.StatusOK, models.CuratedCollectionResponse{CuratedCollection: *collection, Movies: movies})
+	}
+}
+
+// ListAllCollections returns every collection, including scheduled and
+// expired ones.
+func ListAllCollections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage collections"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		collections, err := database.FindAll[models.CuratedCollection](ctx, database.CuratedCollections, bson.M{}, collectionOrder)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching collections"})
+			return
+		}
+		c.JSON(http.StatusOK, collections)
+	}
+}
+
+// CreateCollection adds a manual or smart collection.
+func CreateCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage collections"})
+			return
+		}
+		userId, _ := utils.GetUserIdFromContext(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		req, ok := bindCollectionRequest(c, ctx)
+		if !ok {
+			return
+		}
+		now := time.Now()
+		collection := models.CuratedCollection{
+			CollectionID: bson.NewObjectID().Hex(),
+			Name:         req.Name,
+			Description:  req.Description,
+			Kind:         req.Kind,
+			ImdbIDs:      req.ImdbIDs,
+			Rules:        req.Rules,
+			Limit:        req.Limit,
+			Position:     req.Position,
+			VisibleFrom:  req.VisibleFrom,
+			VisibleUntil: req.VisibleUntil,
+			CreatedBy:    userId,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+		if _, err := database.CuratedCollections.InsertOne(ctx, collection); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating collection"})
+			return
+		}
+		c.JSON(http.StatusCreated, collection)
+	}
+}
+
+// UpdateCollection replaces a collection's editable fields.
+func UpdateCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage collections"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		req, ok := bindCollectionRequest(c, ctx)
+		if !ok {
+			return
+		}
+		set := bson.M{
+			"name":        req.Name,
+			"description": req.Description,
+			"kind":        req.Kind,
+			"imdb_ids":    req.ImdbIDs,
+			"limit":       req.Limit,
+			"position":    req.Position,
+			"updated_at":  time.Now(),
+		}
+		// Rules and the schedule are optional, so clearing them in the
+		// request removes them from the stored collection.
+		unset := bson.M{}
+		if req.Rules != nil {
+			set["rules"] = req.Rules
+		} else {
+			unset["rules"] = ""
+		}
+		if req.VisibleFrom != nil {
+			set["visible_from"] = req.VisibleFrom
+		} else {
+			unset["visible_from"] = ""
+		}
+		if req.VisibleUntil != nil {
+			set["visible_until"] = req.VisibleUntil
+		} else {
+			unset["visible_until"] = ""
+		}
+		update := bson.M{"$set": set}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		var collection models.CuratedCollection
+		err = database.CuratedCollections.FindOneAndUpdate(ctx, bson.M{"collection_id": c.Param("collection_id")}, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&collection)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating collection"})
+			return
+		}
+		c.JSON(http.StatusOK, collection)
+	}
+}
+
+// SetCollectionMovies replaces the movies of a manual collection, in the
+// order given.
+func SetCollectionMovies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage collections"})
+			return
+		}
+		var req struct {
+			ImdbIDs []string `json:"imdb_ids" validate:"required,max=500,unique,dive,required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		missing, err := missingMovies(ctx, req.ImdbIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking movies"})
+			return
+		}
+		if len(missing) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Some movies do not exist", "missing": missing})
+			return
+		}
+		result, err := database.CuratedCollections.UpdateOne(ctx,
+			bson.M{"collection_id": c.Param("collection_id"), "kind": models.CollectionManual},
+			bson.M{"$set": bson.M{"imdb_ids": req.ImdbIDs, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating collection"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Manual collection not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"imdb_ids": req.ImdbIDs})
+	}
+}
+
+// ReorderCollections sets each listed collection's position to its index.
+func ReorderCollections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage collections"})
+			return
+		}
+		var req struct {
+			CollectionIDs []string `json:"collection_ids" validate:"required,min=1,unique,dive,required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		now := time.Now()
+		writes := make([]mongo.WriteModel, 0, len(req.CollectionIDs))
+		for i, id := range req.CollectionIDs {
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"collection_id": id}).
+				SetUpdate(bson.M{"$set": bson.M{"position": i, "updated_at": now}}))
+		}
+		if _, err := database.CuratedCollections.BulkWrite(ctx, writes); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reordering collections"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Collections reordered successfully"})
+	}
+}
+
+// DeleteCollection removes a collection. Its movies are left alone.
+func DeleteCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage collections"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		result, err := database.CuratedCollections.DeleteOne(ctx, bson.M{"collection_id": c.Param("collection_id")})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting collection"})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Collection deleted successfully"})
+	}
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/mailer"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// verificationResendInterval is the minimum time between two verification
+// emails for the same account.
+func verificationResendInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_RESEND_INTERVAL")); err == nil {
+		return d
+	}
+	return time.Minute
+}
+
+func sendVerificationEmail(ctx context.Context, user models.User) error {
+	token, err := utils.GenerateVerificationToken(user.UserID, user.Email)
+	if err != nil {
+		return err
+	}
+	_, err = database.Users.UpdateOne(ctx, bson.M{"user_id": user.UserID}, bson.M{"$set": bson.M{"verification_sent_at": time.Now()}})
+	if err != nil {
+		return err
+	}
+	return mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your MagicStream email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below.\n\n%s\n",
+			user.FirstName, appURL("/verify-email?token="+token)),
+	})
+}
+
+func VerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification token is required"})
+			return
+		}
+		claims, err := utils.ValidateVerificationToken(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		result, err := database.Users.UpdateOne(ctx, bson.M{"user_id": claims.UserID, "email": claims.Email}, bson.M{
+			"$set": bson.M{"email_verified": true, "updated_at": time.Now()},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying email"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification link is invalid or has expired"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+	}
+}
+
+// ResendVerificationEmail answers 202 whether or not a mail was sent so it
+// does not reveal which addresses are registered or already verified.
+func ResendVerificationEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Email string `json:"email" validate:"required,email"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+		accepted := gin.H{"message": "If the account exists and is unverified, a new verification email has been sent"}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+
+		var user models.User
+		err := database.Users.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusAccepted, accepted)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up user"})
+			return
+		}
+		// Throttled resends are dropped silently for the same reason.
+		if user.EmailVerified || time.Since(user.VerificationSentAt) < verificationResendInterval() {
+			c.JSON(http.StatusAccepted, accepted)
+			return
+		}
+		if err := sendVerificationEmail(ctx, user); err != nil {
+			logging.FromContext(c, logger).Error("error sending verification email", "error", err)
+		}
+		c.JSON(http.StatusAccepted, accepted)
+	}
+}
@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Kinds of home feed rail. Kinds marked "one per" may produce several rails.
+const (
+	RailContinueWatching = "continue_watching"
+	RailRecommended      = "recommended"
+	RailTopByGenre       = "top_by_genre" // one per favourite genre
+	RailNewArrivals      = "new_arrivals"
+	RailCollections      = "collections" // one per visible curated collection
+)
+
+const (
+	maxGenreRails      = 3
+	maxCollectionRails = 5
+)
+
+// railRequest is what every rail builder gets to work with.
+type railRequest struct {
+	userId    string
+	favGenres []string
+	// size is how many movies to fetch. It is larger than the rail will
+	// show, so rails still fill up after duplicates are removed.
+	size int
+}
+
+type railBuilder func(ctx context.Context, req railRequest) ([]models.FeedRail, error)
+
+var railBuilders = map[string]railBuilder{
+	RailContinueWatching: continueWatchingRail,
+	RailRecommended:      recommendedRail,
+	RailTopByGenre:       topByGenreRails,
+	RailNewArrivals:      newArrivalsRail,
+	RailCollections:      collectionRails,
+}
+
+type feedConfig struct {
+	// rails is the default set of rail kinds, in display order.
+	rails    []string
+	railSize int
+	timeout  time.Duration
+	cacheTTL time.Duration
+}
+
+var feedSettings = loadFeedConfig()
+
+// feedCache keeps one entry per user, so it has its own budget, sized by
+// FEED_CACHE_MAX_ENTRIES and FEED_CACHE_MAX_BYTES, rather than evicting the
+// shared catalogue responses.
+var feedCache = loadCache("FEED_CACHE", 10000, 64<<20)
+
+func loadFeedConfig() feedConfig {
+	cfg := feedConfig{
+		rails:    []string{RailContinueWatching, RailRecommended, RailTopByGenre, RailNewArrivals, RailCollections},
+		railSize: 20,
+		timeout:  2 * time.Second,
+		cacheTTL: 2 * time.Minute,
+	}
+	if v := os.Getenv("FEED_RAILS"); v != "" {
+		rails, err := parseRails(v)
+		if err != nil {
+			logger.Error("invalid FEED_RAILS", "error", err)
+			os.Exit(1)
+		}
+		cfg.rails = rails
+	}
+	if v, err := strconv.Atoi(os.Getenv("FEED_RAIL_SIZE")); err == nil && v > 0 {
+		cfg.railSize = v
+	}
+	if d, err := time.ParseDuration(os.Getenv("FEED_RAIL_TIMEOUT")); err == nil && d > 0 {
+		cfg.timeout = d
+	}
+	// A TTL of 0 turns the cache off.
+	if d, err := time.ParseDuration(os.Getenv("FEED_CACHE_TTL")); err == nil && d >= 0 {
+		cfg.cacheTTL = d
+	}
+	return cfg
+}
+
+// parseRails reads a comma-separated list of rail kinds.
+func parseRails(v string) ([]string, error) {
+	var rails []string
+	seen := map[string]bool{}
+	for _, kind := range strings.Split(v, ",") {
+		kind = strings.TrimSpace(kind)
+		if _, ok := railBuilders[kind]; !ok {
+			return nil, fmt.Errorf("unknown rail %q", kind)
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			rails = append(rails, kind)
+		}
+	}
+	return rails, nil
+}
+
+func feedCacheKey(userId string, kinds []string) string {
+	return feedCachePrefix(userId) + strings.Join(kinds, ",")
+}
+
+func feedCachePrefix(userId string) string {
+	return "feed:" + userId + "|"
+}
+
+// invalidateFeed drops every cached feed for userId.
+func invalidateFeed(c *gin.Context, userId string) {
+	if err := feedCache.DeletePrefix(c.Request.Context(), feedCachePrefix(userId)); err != nil {
+		logging.FromContext(c, logger).Warn("error invalidating feed cache", "error", err)
+	}
+}
+
+// GetFeed returns the caller's home page as a list of rails. The rails
+// query parameter picks which kinds to build, in order; by default the
+// FEED_RAILS setting is used. Rails are built concurrently, each with its
+// own timeout, and a rail that fails or times out is left out rather than
+// failing the feed. A movie is only shown on the first rail it appears on.
+func GetFeed() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		kinds := feedSettings.rails
+		if v := c.Query("rails"); v != "" {
+			if kinds, err = parseRails(v); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		cacheKey := feedCacheKey(userId, kinds)
+		if feedSettings.cacheTTL > 0 {
+			data, ok, err := feedCache.Get(c.Request.Context(), cacheKey)
+			if err != nil {
+				logging.FromContext(c, logger).Warn("feed cache lookup failed", "error", err)
+			}
+			if ok {
+				metrics.FeedCache.WithLabelValues("hit").Inc()
+				c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+				return
+			}
+			metrics.FeedCache.WithLabelValues("miss").Inc()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		favGenres, err := GetUserFavouriteGenres(ctx, userId)
+		if err != nil {
+			logging.FromContext(c, logger).Warn("could not load favourite genres for feed", "error", err)
+		}
+		req := railRequest{userId: userId, favGenres: favGenres, size: 2 * feedSettings.railSize}
+
+		results := make([][]models.FeedRail, len(kinds))
+		failed := make([]bool, len(kinds))
+		var wg sync.WaitGroup
+		for i, kind := range kinds {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				railCtx, cancel := context.WithTimeout(ctx, feedSettings.timeout)
+				defer cancel()
+				rails, err := railBuilders[kind](railCtx, req)
+				outcome := "success"
+				switch {
+				case err != nil && errors.Is(railCtx.Err(), context.DeadlineExceeded):
+					outcome = "timeout"
+				case err != nil:
+					outcome = "failure"
+				}
+				metrics.FeedRails.WithLabelValues(kind, outcome).Inc()
+				if err != nil {
+					logging.FromContext(c, logger).Warn("feed rail failed", "rail", kind, "outcome", outcome, "error", err)
+					failed[i] = true
+					return
+				}
+				results[i] = rails
+			}()
+		}
+		wg.Wait()
+
+		feed := models.FeedResponse{Rails: []models.FeedRail{}, GeneratedAt: time.Now()}
+		shown := map[string]bool{}
+		complete := true
+		for i := range kinds {
+			if failed[i] {
+				complete = false
+				continue
+			}
+			for _, rail := range results[i] {
+				movies := make([]models.Movie, 0, feedSettings.railSize)
+				for _, movie := range rail.Movies {
+					if len(movies) == feedSettings.railSize {
+						break
+					}
+					if shown[movie.ImdbID] {
+						continue
+					}
+					shown[movie.ImdbID] = true
+					movies = append(movies, movie)
+				}
+				if len(movies) == 0 {
+					continue
+				}
+				rail.Movies = movies
+				if rail.Progress != nil {
+					progress := make(map[string]int, len(movies))
+					for _, movie := range movies {
+						progress[movie.ImdbID] = rail.Progress[movie.ImdbID]
+					}
+					rail.Progress = progress
+				}
+				feed.Rails = append(feed.Rails, rail)
+			}
+		}
+		data, err := json.Marshal(feed)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error encoding feed"})
+			return
+		}
+		// A feed missing a rail is not cached, so the next visit retries it.
+		if complete && feedSettings.cacheTTL > 0 {
+			if err := feedCache.Set(ctx, cacheKey, data, feedSettings.cacheTTL); err != nil {
+				logging.FromContext(c, logger).Warn("feed cache store failed", "error", err)
+			}
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}
+}
+
+// moviesInOrder fetches the movies in imdbIds, keeping their order.
+func moviesInOrder(ctx context.Context, imdbIds []string) ([]models.Movie, error) {
+	found, err := database.FindAll[models.Movie](ctx, database.Movies, bson.M{"imdb_id": bson.M{"$in": imdbIds}})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Movie, len(found))
+	for _, movie := range found {
+		byID[movie.ImdbID] = movie
+	}
+	movies := make([]models.Movie, 0, len(imdbIds))
+	for _, id := range imdbIds {
+		if movie, ok := byID[id]; ok {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+func continueWatchingRail(ctx context.Context, req railRequest) ([]models.FeedRail, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}).SetLimit(int64(req.size))
+	progress, err := database.FindAll[models.WatchProgress](ctx, database.WatchProgress,
+		bson.M{"user_id": req.userId, "completed": false, "position_seconds": bson.M{"$gt": 0}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(progress))
+	positions := make(map[string]int, len(progress))
+	for _, p := range progress {
+		ids = append(ids, p.ImdbID)
+		positions[p.ImdbID] = p.PositionSeconds
+	}
+	movies, err := moviesInOrder(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return []models.FeedRail{{
+		ID:       RailContinueWatching,
+		Kind:     RailContinueWatching,
+		Title:    "Continue Watching",
+		Movies:   movies,
+		Progress: positions,
+	}}, nil
+}
+
+// bestRanked returns movies matching filter, best ranked first.
+func bestRanked(ctx context.Context, filter bson.M, size int) ([]models.Movie, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "ranking.ranking_value", Value: 1}}).SetLimit(int64(size))
+	return database.FindAll[models.Movie](ctx, database.Movies, filter, opts)
+}
+
+func recommendedRail(ctx context.Context, req railRequest) ([]models.FeedRail, error) {
+	if len(req.favGenres) == 0 {
+		return nil, nil
+	}
+	movies, err := bestRanked(ctx, bson.M{"genre.genre_name": bson.M{"$in": req.favGenres}}, req.size)
+	if err != nil {
+		return nil, err
+	}
+	return []models.FeedRail{{ID: RailRecommended, Kind: RailRecommended, Title: "Recommended For You", Movies: movies}}, nil
+}
+
+func topByGenreRails(ctx context.Context, req railRequest) ([]models.FeedRail, error) {
+	genres := req.favGenres
+	if len(genres) > maxGenreRails {
+		genres = genres[:maxGenreRails]
+	}
+	rails := make([]models.FeedRail, 0, len(genres))
+	for _, genre := range genres {
+		movies, err := bestRanked(ctx, bson.M{
+			"genre.genre_name":      genre,
+			"ranking.ranking_value": bson.M{"$ne": models.NotRanked.RankingValue},
+		}, req.size)
+		if err != nil {
+			return nil, err
+		}
+		rails = append(rails, models.FeedRail{
+			ID:     RailTopByGenre + ":" + genre,
+			Kind:   RailTopByGenre,
+			Title:  "Top " + genre,
+			Movies: movies,
+		})
+	}
+	return rails, nil
+}
+
+// newArrivalsRail lists the movies most recently added to the catalogue,
+// which is the order of their ObjectIDs.
+func newArrivalsRail(ctx context.Context, req railRequest) ([]models.FeedRail, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(req.size))
+	movies, err := database.FindAll[models.Movie](ctx, database.Movies, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return []models.FeedRail{{ID: RailNewArrivals, Kind: RailNewArrivals, Title: "New Arrivals", Movies: movies}}, nil
+}
+
+func collectionRails(ctx context.Context, req railRequest) ([]models.FeedRail, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}}).
+		SetLimit(maxCollectionRails)
+	collections, err := database.FindAll[models.CuratedCollection](ctx, database.CuratedCollections, visibleNow(), opts)
+	if err != nil {
+		return nil, err
+	}
+	rails := make([]models.FeedRail, 0, len(collections))
+	for i := range collections {
+		movies, err := collectionMovies(ctx, &collections[i])
+		if err != nil {
+			return nil, err
+		}
+		rails = append(rails, models.FeedRail{
+			ID:     RailCollections + ":" + collections[i].CollectionID,
+			Kind:   RailCollections,
+			Title:  collections[i].Name,
+			Movies: movies,
+		})
+	}
+	return rails, nil
+}
@@ -0,0 +1,674 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/stats"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	maxListMovies    = 1000
+	publicListsLimit = 50
+)
+
+var errListNotFound = errors.New("list not found")
+
+// newShareToken returns a random token for an unlisted list's link.
+func newShareToken() (string, error) {
+	token, _, err := utils.GenerateOpaqueToken()
+	return token, err
+}
+
+// findViewableList loads a list userId may see: one of their own, or a
+// public list. Other lists are reported as not found.
+func findViewableList(ctx context.Context, listId, userId string) (*models.UserList, error) {
+	list, err := database.FindOne[models.UserList](ctx, database.UserLists, bson.M{"list_id": listId})
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, errListNotFound
+		}
+		return nil, err
+	}
+	if list.OwnerID != userId && list.Privacy != models.ListPublic {
+		return nil, errListNotFound
+	}
+	return list, nil
+}
+
+// listMovies returns summaries of imdbIds in the same order. Movies that
+// have since left the catalogue are skipped.
+func listMovies(ctx context.Context, imdbIds []string) ([]models.MovieSummary, error) {
+	found, err := database.FindAll[models.MovieSummary](ctx, database.Movies,
+		bson.M{"imdb_id": bson.M{"$in": imdbIds}}, options.Find().SetProjection(summaryProjection))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.MovieSummary, len(found))
+	for _, movie := range found {
+		byID[movie.ImdbID] = movie
+	}
+	movies := make([]models.MovieSummary, 0, len(imdbIds))
+	for _, id := range imdbIds {
+		if movie, ok := byID[id]; ok {
+			movies = append(movies, movie)
+		}
+	}
+	return movies, nil
+}
+
+// respondWithList writes list together with its movies.
+func respondWithList(c *gin.Context, ctx context.Context, status int, list *models.UserList) {
+	movies, err := listMovies(ctx, list.ImdbIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching list movies"})
+		return
+	}
+	c.JSON(status, models.UserListResponse{UserList: *list, Movies: movies})
+}
+
+// checkListMovies writes a 400 and returns false when any of imdbIds is not
+// in the catalogue.
+func checkListMovies(c *gin.Context, ctx context.Context, imdbIds []string) bool {
+	missing, err := missingMovies(ctx, imdbIds)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking movies"})
+		return false
+	}
+	if len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some movies do not exist", "missing": missing})
+		return false
+	}
+	return true
+}
+
+// insertList assigns list an ID, and a share token when it is unlisted,
+// then stores it. Every movie in it counts as added to a list.
+func insertList(ctx context.Context, list *models.UserList) error {
+	now := time.Now()
+	list.ListID = bson.NewObjectID().Hex()
+	list.CreatedAt = now
+	list.UpdatedAt = now
+	if list.ImdbIDs == nil {
+		list.ImdbIDs = []string{}
+	}
+	if list.Privacy == models.ListUnlisted {
+		token, err := newShareToken()
+		if err != nil {
+			return err
+		}
+		list.ShareToken = token
+	}
+	if _, err := database.UserLists.InsertOne(ctx, list); err != nil {
+		return err
+	}
+	for _, id := range list.ImdbIDs {
+		stats.Record(id, stats.EventListAdd)
+	}
+	return nil
+}
+
+func CreateList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req models.CreateListRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list data"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		if !checkListMovies(c, ctx, req.ImdbIDs) {
+			return
+		}
+		list := models.UserList{
+			OwnerID:     userId,
+			Title:       strings.TrimSpace(req.Title),
+			Description: req.Description,
+			Privacy:     req.Privacy,
+			ImdbIDs:     req.ImdbIDs,
+		}
+		if list.Privacy == "" {
+			list.Privacy = models.ListPrivate
+		}
+		if err := insertList(ctx, &list); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error creating list"})
+			return
+		}
+		respondWithList(c, ctx, http.StatusCreated, &list)
+	}
+}
+
+// ListMyLists returns the caller's own lists, most recently changed first.
+func ListMyLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		lists, err := database.FindAll[models.UserList](ctx, database.UserLists, bson.M{"owner_id": userId},
+			options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching lists"})
+			return
+		}
+		c.JSON(http.StatusOK, lists)
+	}
+}
+
+// SearchPublicLists lists public lists whose title contains the search
+// parameter, most followed first and at most 50 at a time.
+func SearchPublicLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		filter := bson.M{"privacy": models.ListPublic}
+		if search := strings.TrimSpace(c.Query("search")); search != "" {
+			filter["title"] = bson.M{"$regex": regexp.QuoteMeta(search), "$options": "i"}
+		}
+		opts := options.Find().
+			SetSort(bson.D{{Key: "follower_count", Value: -1}, {Key: "updated_at", Value: -1}}).
+			SetLimit(publicListsLimit)
+		lists, err := database.FindAll[models.UserList](ctx, database.UserLists, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching lists"})
+			return
+		}
+		c.JSON(http.StatusOK, lists)
+	}
+}
+
+// ListUserPublicLists returns another user's public lists.
+func ListUserPublicLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		lists, err := database.FindAll[models.UserList](ctx, database.UserLists,
+			bson.M{"owner_id": c.Param("user_id"), "privacy": models.ListPublic},
+			options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching lists"})
+			return
+		}
+		c.JSON(http.StatusOK, lists)
+	}
+}
+
+// GetList returns one of the caller's lists or a public list, with its movies.
+func GetList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		list, err := findViewableList(ctx, c.Param("list_id"), userId)
+		if err == errListNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching list"})
+			return
+		}
+		respondWithList(c, ctx, http.StatusOK, list)
+	}
+}
+
+// GetSharedList returns an unlisted list to anyone holding its share token.
+func GetSharedList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		list, err := database.FindOne[models.UserList](ctx, database.UserLists,
+			bson.M{"share_token": c.Param("share_token"), "privacy": models.ListUnlisted})
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		list.ShareToken = ""
+		respondWithList(c, ctx, http.StatusOK, list)
+	}
+}
+
+// UpdateList changes a list's title, description or privacy. Making a list
+// unlisted issues a share token; any other privacy revokes it.
+func UpdateList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req models.UpdateListRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid list data"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		filter := bson.M{"list_id": c.Param("list_id"), "owner_id": userId}
+		list, err := database.FindOne[models.UserList](ctx, database.UserLists, filter)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		set := bson.M{"updated_at": time.Now()}
+		update := bson.M{"$set": set}
+		if req.Title != nil {
+			set["title"] = strings.TrimSpace(*req.Title)
+		}
+		if req.Description != nil {
+			set["description"] = *req.Description
+		}
+		if req.Privacy != nil && *req.Privacy != list.Privacy {
+			set["privacy"] = *req.Privacy
+			if *req.Privacy == models.ListUnlisted {
+				token, err := newShareToken()
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating share token"})
+					return
+				}
+				set["share_token"] = token
+			} else {
+				update["$unset"] = bson.M{"share_token": ""}
+			}
+		}
+		var updated models.UserList
+		err = database.UserLists.FindOneAndUpdate(ctx, filter, update,
+			options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating list"})
+			return
+		}
+		respondWithList(c, ctx, http.StatusOK, &updated)
+	}
+}
+
+// RotateListShareToken replaces an unlisted list's share token so links
+// handed out earlier stop working.
+func RotateListShareToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		token, err := newShareToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating share token"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		result, err := database.UserLists.UpdateOne(ctx,
+			bson.M{"list_id": c.Param("list_id"), "owner_id": userId, "privacy": models.ListUnlisted},
+			bson.M{"$set": bson.M{"share_token": token, "updated_at": time.Now()}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating list"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unlisted list not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"share_token": token})
+	}
+}
+
+// SetListMovies replaces the movies in one of the caller's lists, in the
+// order given.
+func SetListMovies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req struct {
+			ImdbIDs []string `json:"imdb_ids" validate:"required,max=1000,unique,dive,required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		if !checkListMovies(c, ctx, req.ImdbIDs) {
+			return
+		}
+		now := time.Now()
+		var list models.UserList
+		err = database.UserLists.FindOneAndUpdate(ctx,
+			bson.M{"list_id": c.Param("list_id"), "owner_id": userId},
+			bson.M{"$set": bson.M{"imdb_ids": req.ImdbIDs, "updated_at": now}},
+			options.FindOneAndUpdate().SetReturnDocument(options.Before),
+		).Decode(&list)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating list"})
+			return
+		}
+		previous := map[string]bool{}
+		for _, id := range list.ImdbIDs {
+			previous[id] = true
+		}
+		for _, id := range req.ImdbIDs {
+			if !previous[id] {
+				stats.Record(id, stats.EventListAdd)
+			}
+		}
+		list.ImdbIDs = req.ImdbIDs
+		list.UpdatedAt = now
+		respondWithList(c, ctx, http.StatusOK, &list)
+	}
+}
+
+// AddListMovie appends a movie to the end of one of the caller's lists.
+// Adding a movie that is already in the list changes nothing.
+func AddListMovie() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req struct {
+			ImdbID string `json:"imdb_id" validate:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		if !checkListMovies(c, ctx, []string{req.ImdbID}) {
+			return
+		}
+		filter := bson.M{"list_id": c.Param("list_id"), "owner_id": userId}
+		list, err := database.FindOne[models.UserList](ctx, database.UserLists, filter)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if len(list.ImdbIDs) >= maxListMovies {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "List is full"})
+			return
+		}
+		result, err := database.UserLists.UpdateOne(ctx, filter, bson.M{
+			"$addToSet": bson.M{"imdb_ids": req.ImdbID},
+			"$set":      bson.M{"updated_at": time.Now()},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating list"})
+			return
+		}
+		if result.ModifiedCount > 0 && !slices.Contains(list.ImdbIDs, req.ImdbID) {
+			stats.Record(req.ImdbID, stats.EventListAdd)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Movie added to list"})
+	}
+}
+
+func RemoveListMovie() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		result, err := database.UserLists.UpdateOne(ctx,
+			bson.M{"list_id": c.Param("list_id"), "owner_id": userId},
+			bson.M{
+				"$pull": bson.M{"imdb_ids": c.Param("imdb_id")},
+				"$set":  bson.M{"updated_at": time.Now()},
+			},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating list"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Movie removed from list"})
+	}
+}
+
+// DeleteList removes one of the caller's lists and everyone's follows of it.
+func DeleteList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		listId := c.Param("list_id")
+		result, err := database.UserLists.DeleteOne(ctx, bson.M{"list_id": listId, "owner_id": userId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting list"})
+			return
+		}
+		if result.DeletedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if _, err := database.ListFollows.DeleteMany(ctx, bson.M{"list_id": listId}); err != nil {
+			logging.FromContext(c, logger).Error("error removing list follows", "listId", listId, "error", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "List deleted successfully"})
+	}
+}
+
+// FollowList follows another user's public list. Following a list twice
+// changes nothing.
+func FollowList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		listId := c.Param("list_id")
+		list, err := database.FindOne[models.UserList](ctx, database.UserLists,
+			bson.M{"list_id": listId, "privacy": models.ListPublic})
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if list.OwnerID == userId {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow your own list"})
+			return
+		}
+		_, err = database.ListFollows.InsertOne(ctx, models.ListFollow{ListID: listId, UserID: userId, FollowedAt: time.Now()})
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusOK, gin.H{"message": "Already following list"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error following list"})
+			return
+		}
+		if _, err := database.UserLists.UpdateOne(ctx, bson.M{"list_id": listId}, bson.M{"$inc": bson.M{"follower_count": 1}}); err != nil {
+			logging.FromContext(c, logger).Error("error counting list follower", "listId", listId, "error", err)
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Following list"})
+	}
+}
+
+func UnfollowList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		listId := c.Param("list_id")
+		result, err := database.ListFollows.DeleteOne(ctx, bson.M{"list_id": listId, "user_id": userId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unfollowing list"})
+			return
+		}
+		if result.DeletedCount > 0 {
+			if _, err := database.UserLists.UpdateOne(ctx, bson.M{"list_id": listId}, bson.M{"$inc": bson.M{"follower_count": -1}}); err != nil {
+				logging.FromContext(c, logger).Error("error counting list follower", "listId", listId, "error", err)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Unfollowed list"})
+	}
+}
+
+// ListFollowedLists returns the lists the caller follows, most recently
+// followed first. Lists their owner has since made non-public are left out
+// until they are public again.
+func ListFollowedLists() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		follows, err := database.FindAll[models.ListFollow](ctx, database.ListFollows, bson.M{"user_id": userId},
+			options.Find().SetSort(bson.D{{Key: "followed_at", Value: -1}}))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching followed lists"})
+			return
+		}
+		ids := make([]string, 0, len(follows))
+		for _, follow := range follows {
+			ids = append(ids, follow.ListID)
+		}
+		found, err := database.FindAll[models.UserList](ctx, database.UserLists,
+			bson.M{"list_id": bson.M{"$in": ids}, "privacy": models.ListPublic})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching followed lists"})
+			return
+		}
+		byID := make(map[string]models.UserList, len(found))
+		for _, list := range found {
+			byID[list.ListID] = list
+		}
+		lists := make([]models.UserList, 0, len(found))
+		for _, id := range ids {
+			if list, ok := byID[id]; ok {
+				lists = append(lists, list)
+			}
+		}
+		c.JSON(http.StatusOK, lists)
+	}
+}
+
+// cloneList copies source into a new private list owned by userId.
+func cloneList(c *gin.Context, ctx context.Context, userId string, source *models.UserList) {
+	list := models.UserList{
+		OwnerID:     userId,
+		Title:       source.Title,
+		Description: source.Description,
+		Privacy:     models.ListPrivate,
+		ImdbIDs:     source.ImdbIDs,
+		ClonedFrom:  source.ListID,
+	}
+	if err := insertList(ctx, &list); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error cloning list"})
+		return
+	}
+	respondWithList(c, ctx, http.StatusCreated, &list)
+}
+
+// CloneList copies one of the caller's lists or a public list into a new
+// private list owned by the caller.
+func CloneList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		source, err := findViewableList(ctx, c.Param("list_id"), userId)
+		if err == errListNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching list"})
+			return
+		}
+		cloneList(c, ctx, userId, source)
+	}
+}
+
+// CloneSharedList copies an unlisted list, found by its share token, into a
+// new private list owned by the caller.
+func CloneSharedList() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		source, err := database.FindOne[models.UserList](ctx, database.UserLists,
+			bson.M{"share_token": c.Param("share_token"), "privacy": models.ListUnlisted})
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+			return
+		}
+		cloneList(c, ctx, userId, source)
+	}
+}
@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	defaultLockoutThreshold = 5
+	baseLockoutDuration     = time.Minute
+	maxLockoutDuration      = 24 * time.Hour
+)
+
+func lockoutThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("LOGIN_LOCKOUT_THRESHOLD")); err == nil && v > 0 {
+		return v
+	}
+	return defaultLockoutThreshold
+}
+
+// lockoutDuration doubles for every failure past the threshold, so repeated
+// guessing after a lock expires is locked out for progressively longer.
+func lockoutDuration(attempts int) time.Duration {
+	over := attempts - lockoutThreshold()
+	if over < 0 {
+		return 0
+	}
+	if over > 10 {
+		return maxLockoutDuration
+	}
+	return min(baseLockoutDuration<<over, maxLockoutDuration)
+}
+
+func recordFailedLogin(ctx context.Context, userId string) error {
+	var user struct {
+		FailedLoginAttempts int `bson:"failed_login_attempts"`
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"failed_login_attempts": 1})
+	err := database.Users.FindOneAndUpdate(ctx, bson.M{"user_id": userId}, bson.M{"$inc": bson.M{"failed_login_attempts": 1}}, opts).Decode(&user)
+	if err != nil {
+		return err
+	}
+	lockFor := lockoutDuration(user.FailedLoginAttempts)
+	if lockFor == 0 {
+		return nil
+	}
+	_, err = database.Users.UpdateOne(ctx, bson.M{"user_id": userId}, bson.M{"$set": bson.M{"locked_until": time.Now().Add(lockFor)}})
+	return err
+}
+
+func resetFailedLogins(ctx context.Context, userId string) error {
+	_, err := database.Users.UpdateOne(ctx, bson.M{"user_id": userId}, bson.M{
+		"$set":   bson.M{"failed_login_attempts": 0},
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+// clearFailedLogins resets the failure count after a complete login. Errors
+// are only logged since the login itself succeeded.
+func clearFailedLogins(c *gin.Context, ctx context.Context, user models.User) {
+	if user.FailedLoginAttempts == 0 {
+		return
+	}
+	if err := resetFailedLogins(ctx, user.UserID); err != nil {
+		logging.FromContext(c, logger).Error("error resetting failed logins", "error", err)
+	}
+}
@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metadata"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var metadataProvider = loadMetadataProvider()
+
+func loadMetadataProvider() metadata.Provider {
+	p, err := metadata.FromEnv()
+	if err != nil {
+		logger.Error("error configuring metadata provider", "error", err)
+		os.Exit(1)
+	}
+	return p
+}
+
+// lookupMetadata fetches metadata for imdbId and writes the error response
+// itself when that fails.
+func lookupMetadata(c *gin.Context, ctx context.Context, imdbId string) (*metadata.Metadata, bool) {
+	md, err := metadataProvider.Lookup(ctx, imdbId)
+	switch {
+	case err == nil:
+		return md, true
+	case errors.Is(err, metadata.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Metadata provider is not configured"})
+	case errors.Is(err, metadata.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found at metadata provider"})
+	default:
+		logging.FromContext(c, logger).Error("error fetching movie metadata", "imdbId", imdbId, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Error fetching movie metadata"})
+	}
+	return nil, false
+}
+
+// GetMovieMetadata returns what the provider knows about an IMDb ID together
+// with a movie pre-filled from it, ready to be reviewed and sent to AddMovie.
+func GetMovieMetadata() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can look up movie metadata"})
+			return
+		}
+		imdbId := c.Param("imdb_id")
+		if !metadata.ValidImdbID(imdbId) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A valid IMDb ID is required"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		md, ok := lookupMetadata(c, ctx, imdbId)
+		if !ok {
+			return
+		}
+		genres, err := database.FindGenres(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching genres from database"})
+			return
+		}
+		movie := models.Movie{ImdbID: imdbId}
+		unmapped := metadata.Apply(&movie, md, genres, false)
+		c.JSON(http.StatusOK, gin.H{"metadata": md, "movie": movie, "unmapped_genres": unmapped})
+	}
+}
+
+// RefreshMovieMetadata overwrites a movie's details with the provider's
+// current data. The admin review and ranking are left alone.
+func RefreshMovieMetadata() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can refresh movie metadata"})
+			return
+		}
+		imdbId := c.Param("imdb_id")
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		movie, err := database.FindMovieByImdbID(ctx, imdbId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+			return
+		}
+		md, ok := lookupMetadata(c, ctx, imdbId)
+		if !ok {
+			return
+		}
+		genres, err := database.FindGenres(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching genres from database"})
+			return
+		}
+		previousPeople := database.CreditedPeople(movie)
+		unmapped := metadata.Apply(movie, md, genres, true)
+		if err := validate.Struct(movie); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+		if err := database.LinkPeople(ctx, movie); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error linking cast and crew"})
+			return
+		}
+		_, err = database.Movies.UpdateOne(ctx, bson.M{"imdb_id": imdbId}, bson.M{"$set": bson.M{
+			"title":            movie.Title,
+			"poster_path":      movie.PosterPath,
+			"youtube_id":       movie.YouTubeID,
+			"genre":            movie.Genre,
+			"release_date":     movie.ReleaseDate,
+			"runtime_minutes":  movie.RuntimeMinutes,
+			"synopsis":         movie.Synopsis,
+			"cast":             movie.Cast,
+			"crew":             movie.Crew,
+			"spoken_languages": movie.SpokenLanguages,
+			"country":          movie.Country,
+			"certification":    movie.Certification,
+		}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating movie"})
+			return
+		}
+		if err := database.PrunePeople(ctx, previousPeople); err != nil {
+			logging.FromContext(c, logger).Error("error removing uncredited people", "imdbId", imdbId, "error", err)
+		}
+		invalidateCatalogue(c)
+		c.JSON(http.StatusOK, gin.H{"movie": movie, "unmapped_genres": unmapped})
+	}
+}
+
+// prefillMovie fills fields left empty in an AddMovie request from the
+// metadata provider. Failures are logged and the request is validated as sent.
+func prefillMovie(c *gin.Context, ctx context.Context, movie *models.Movie) {
+	md, err := metadataProvider.Lookup(ctx, movie.ImdbID)
+	if err != nil {
+		if !errors.Is(err, metadata.ErrNotConfigured) {
+			logging.FromContext(c, logger).Warn("could not prefill movie metadata", "imdbId", movie.ImdbID, "error", err)
+		}
+		return
+	}
+	genres, err := database.FindGenres(ctx)
+	if err != nil {
+		logging.FromContext(c, logger).Warn("could not load genres to prefill movie", "error", err)
+		return
+	}
+	metadata.Apply(movie, md, genres, false)
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const recoveryCodeCount = 10
+
+// MFAEnroll starts enrollment with a new secret. It only becomes active once
+// a code generated from it is confirmed.
+func MFAEnroll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		var user models.User
+		if err := database.Users.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if user.MFAEnabled {
+			c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+			return
+		}
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating secret"})
+			return
+		}
+		if _, err := database.Users.UpdateOne(ctx, bson.M{"user_id": userId}, bson.M{"$set": bson.M{"mfa_pending_secret": secret}}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing secret"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"secret":      secret,
+			"otpauth_uri": utils.TOTPURI("MagicStream", user.Email, secret),
+		})
+	}
+}
+
+// MFAConfirm enables two-factor authentication and returns the recovery
+// codes, which are shown only this once. The caller's session is reissued as
+// MFA-verified so admins do not have to log in again.
+func MFAConfirm() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req struct {
+			Code string `json:"code" validate:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || validate.Struct(req) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		var user models.User
+		if err := database.Users.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if user.MFAPendingSecret == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Start enrollment first"})
+			return
+		}
+		step, ok := utils.ValidateTOTP(user.MFAPendingSecret, req.Code, 0)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+			return
+		}
+		codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating recovery codes"})
+			return
+		}
+		_, err = database.Users.UpdateOne(ctx, bson.M{"user_id": userId}, bson.M{
+			"$set": bson.M{
+				"mfa_enabled":          true,
+				"mfa_secret":           user.MFAPendingSecret,
+				"mfa_last_step":        step,
+				"recovery_code_hashes": hashes,
+				"updated_at":           time.Now(),
+			},
+			"$unset": bson.M{"mfa_pending_secret": ""},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error enabling two-factor authentication"})
+			return
+		}
+		response := gin.H{"message": "Two-factor authentication enabled", "recovery_codes": codes}
+		token, refreshToken, err := createSession(ctx, user, true)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating tokens"})
+			return
+		}
+		if c.GetHeader("Authorization") != "" {
+			response["token"] = token
+			response["refresh_token"] = refreshToken
+		} else {
+			setSessionCookies(c, token, refreshToken)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+}
+
+// MFADisable requires a current code so a stolen session alone cannot turn
+// the second factor off.
+func MFADisable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req struct {
+			Code string `json:"code" validate:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || validate.Struct(req) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		var user models.User
+		if err := database.Users.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if !user.MFAEnabled {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+			return
+		}
+		if ok, err := verifySecondFactor(ctx, user, req.Code, ""); err != nil || !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid code"})
+			return
+		}
+		_, err = database.Users.UpdateOne(ctx, bson.M{"user_id": userId}, bson.M{
+			"$set":   bson.M{"mfa_enabled": false, "updated_at": time.Now()},
+			"$unset": bson.M{"mfa_secret": "", "mfa_last_step": "", "recovery_code_hashes": "", "mfa_pending_secret": ""},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error disabling two-factor authentication"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+	}
+}
+
+// LoginMFA is the second step of LoginUser for users with two-factor
+// authentication, exchanging the challenge token and a code for a session.
+func LoginMFA() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.MFALogin
+		if err := c.ShouldBindJSON(&req); err != nil || validate.Struct(req) != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		mfaToken := req.MFAToken
+		if mfaToken == "" {
+			mfaToken, _ = c.Cookie(mfaChallengeCookie)
+		}
+		challenge, err := utils.ValidateMFAChallenge(mfaToken)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "MFA challenge is invalid or has expired, please log in again"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		var user models.User
+		if err := database.Users.FindOne(ctx, bson.M{"user_id": challenge.UserID}).Decode(&user); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+		if time.Until(user.LockedUntil) > 0 {
+			metrics.LoginFailures.WithLabelValues("locked").Inc()
+			c.JSON(http.StatusLocked, gin.H{"error": "Account is temporarily locked due to too many failed login attempts"})
+			return
+		}
+		ok, err := verifySecondFactor(ctx, user, req.Code, req.RecoveryCode)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error verifying code"})
+			return
+		}
+		if !ok {
+			metrics.LoginFailures.WithLabelValues("bad_mfa_code").Inc()
+			if err := recordFailedLogin(ctx, user.UserID); err != nil {
+				logging.FromContext(c, logger).Error("error recording failed login", "error", err)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+			return
+		}
+		clearFailedLogins(c, ctx, user)
+		clearMFAChallengeCookie(c)
+		issueSession(c, ctx, user, req.ReturnTokens, true)
+	}
+}
+
+// verifySecondFactor accepts either a TOTP code or an unused recovery code.
+// Both are consumed atomically so concurrent requests cannot reuse them.
+func verifySecondFactor(ctx context.Context, user models.User, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		hash := utils.HashRecoveryCode(recoveryCode)
+		result, err := database.Users.UpdateOne(ctx,
+			bson.M{"user_id": user.UserID, "recovery_code_hashes": hash},
+			bson.M{"$pull": bson.M{"recovery_code_hashes": hash}})
+		if err != nil {
+			return false, err
+		}
+		return result.ModifiedCount == 1, nil
+	}
+	step, ok := utils.ValidateTOTP(user.MFASecret, code, user.MFALastStep)
+	if !ok {
+		return false, nil
+	}
+	result, err := database.Users.UpdateOne(ctx,
+		bson.M{"user_id": user.UserID, "mfa_last_step": bson.M{"$lt": step}},
+		bson.M{"$set": bson.M{"mfa_last_step": step}})
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount == 1, nil
+}
@@ -3,7 +3,7 @@ package controllers
 import (
 	"context"
 	"errors"
-	"log"
+	"fmt"
 	"net/http"
 	"os"
 	"strconv"
@@ -11,7 +11,11 @@ import (
 	"time"
 
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/stats"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/tracing"
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
@@ -20,73 +24,209 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
-var movieCollection *mongo.Collection = database.OpenCollection("movies")
-var rankingCollection *mongo.Collection = database.OpenCollection("rankings")
-var genreCollection *mongo.Collection = database.OpenCollection("genres")
 var validate = validator.New()
+var logger = logging.For("controllers")
 
+// GetMovies lists the movies matching the query. With stream=ndjson or
+// stream=json the movies are written as they are read from the database
+// rather than collected first, so large listings use little memory.
 func GetMovies() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-		defer cancel()
-		var movies []models.Movie
-		cursor, err := movieCollection.Find(ctx, bson.M{})
+		query, err := parseMovieQuery(c)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching movies from database"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		defer cursor.Close(ctx)
-		if err = cursor.All(ctx, &movies); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding movies from database"})
+		format, err := streamFormat(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		timeout := 100 * time.Second
+		if format != "" {
+			timeout = streamTimeout
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		if format != "" {
+			cursor, err := database.Movies.Find(ctx, database.MovieFilter(query), options.Find().SetBatchSize(streamBatchSize))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching movies from database"})
+				return
+			}
+			streamCursor[models.Movie](ctx, c, cursor, format)
+			return
+		}
+		movies, err := database.FindAll[models.Movie](ctx, database.Movies, database.MovieFilter(query))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching movies from database"})
 			return
 		}
 		c.JSON(http.StatusOK, movies)
 	}
 }
 
+// parseMovieQuery reads the optional GetMovies parameters: genre (comma
+// separated names), year_from, year_to, runtime_min, runtime_max,
+// ranking_max, language, country and certification.
+func parseMovieQuery(c *gin.Context) (models.MovieQuery, error) {
+	var q models.MovieQuery
+	if genres := c.Query("genre"); genres != "" {
+		q.Genres = strings.Split(genres, ",")
+	}
+	for name, dst := range map[string]**int{
+		"year_from":   &q.YearFrom,
+		"year_to":     &q.YearTo,
+		"runtime_min": &q.RuntimeMin,
+		"runtime_max": &q.RuntimeMax,
+		"ranking_max": &q.RankingMax,
+	} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return q, fmt.Errorf("%s must be a non-negative whole number", name)
+		}
+		*dst = &n
+	}
+	q.Language = c.Query("language")
+	q.Country = c.Query("country")
+	q.Certification = c.Query("certification")
+	return q, nil
+}
+
 func GetMovie() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
 		movieID := c.Param("imdb_id")
 		if movieID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Movie ID is required"})
 			return
 		}
-		var movie models.Movie
-		err := movieCollection.FindOne(ctx, bson.M{"imdb_id": movieID}).Decode(&movie)
+		movie, err := database.FindMovieByImdbID(ctx, movieID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "movie not found in database"})
 			return
 		}
-		c.JSON(http.StatusOK, movie)
+		more, err := moreFromDirector(ctx, movie)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching related movies"})
+			return
+		}
+		c.JSON(http.StatusOK, models.MovieResponse{Movie: *movie, MoreFromDirector: more})
+	}
+}
+
+// CountMovieView records a view of a movie page once it has been served,
+// including when it came from the response cache and GetMovie did not run.
+func CountMovieView() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		if status := c.Writer.Status(); status == http.StatusOK || status == http.StatusNotModified {
+			stats.Record(c.Param("imdb_id"), stats.EventView)
+		}
 	}
 }
 
 func AddMovie() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
 		var movie models.Movie
 		if err := c.BindJSON(&movie); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid movie data"})
 			return
 		}
+		prefillMovie(c, ctx, &movie)
+		// Store absent lists as empty arrays, like the backfill migration does.
+		if movie.Cast == nil {
+			movie.Cast = []models.CastMember{}
+		}
+		if movie.Crew == nil {
+			movie.Crew = []models.CrewMember{}
+		}
+		if movie.SpokenLanguages == nil {
+			movie.SpokenLanguages = []string{}
+		}
+		// Credits are always linked by name, so a payload cannot point them
+		// at people that do not exist.
+		for i := range movie.Cast {
+			movie.Cast[i].PersonID = ""
+		}
+		for i := range movie.Crew {
+			movie.Crew[i].PersonID = ""
+		}
 		if err := validate.Struct(movie); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		result, err := movieCollection.InsertOne(ctx, movie)
+		if err := database.LinkPeople(ctx, &movie); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error linking cast and crew"})
+			return
+		}
+		result, err := database.Movies.InsertOne(ctx, movie)
 		if err != nil {
+			// Drop anyone LinkPeople created just for this movie.
+			if err := database.PrunePeople(ctx, database.CreditedPeople(&movie)); err != nil {
+				logging.FromContext(c, logger).Error("error pruning people", "error", err)
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error inserting movie into database"})
 			return
 		}
+		invalidateCatalogue(c)
 		c.JSON(http.StatusCreated, result)
 	}
 }
 
+// DeleteMovie removes a movie from the catalogue and from every curated
+// collection and user list, along with anyone credited only on it.
+func DeleteMovie() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can delete movies"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		var movie models.Movie
+		err = database.Movies.FindOneAndDelete(ctx, bson.M{"imdb_id": c.Param("imdb_id")}).Decode(&movie)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error deleting movie"})
+			return
+		}
+		if err := database.PrunePeople(ctx, database.CreditedPeople(&movie)); err != nil {
+			logging.FromContext(c, logger).Error("error removing uncredited people", "imdbId", movie.ImdbID, "error", err)
+		}
+		for _, lists := range []*mongo.Collection{database.CuratedCollections, database.UserLists} {
+			_, err := lists.UpdateMany(ctx,
+				bson.M{"imdb_ids": movie.ImdbID},
+				bson.M{"$pull": bson.M{"imdb_ids": movie.ImdbID}},
+			)
+			if err != nil {
+				logging.FromContext(c, logger).Error("error removing movie from lists", "imdbId", movie.ImdbID, "collection", lists.Name(), "error", err)
+			}
+		}
+		invalidateCatalogue(c)
+		c.JSON(http.StatusOK, gin.H{"message": "Movie deleted successfully"})
+	}
+}
+
 func AdminReviewUpdate() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, err := utils.GetRoleFromContext(c)
@@ -114,7 +254,7 @@ func AdminReviewUpdate() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 			return
 		}
-		sentiment, rankVal, err := GetReviewRanking(req.AdminReview)
+		sentiment, rankVal, err := GetReviewRanking(c.Request.Context(), req.AdminReview)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error getting review ranking"})
 			return
@@ -127,9 +267,9 @@ func AdminReviewUpdate() gin.HandlerFunc {
 				"ranking_name":  sentiment,
 			},
 		}}
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
-		result, err := movieCollection.UpdateOne(ctx, filter, update)
+		result, err := database.Movies.UpdateOne(ctx, filter, update)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating movie review"})
 			return
@@ -138,31 +278,35 @@ func AdminReviewUpdate() gin.HandlerFunc {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
 			return
 		}
+		invalidateCatalogue(c)
 		resp.RankingName = sentiment
 		resp.AdminReview = req.AdminReview
 		c.JSON(http.StatusOK, resp)
 	}
 }
 
-func GetReviewRanking(admin_review string) (string, int, error) {
-	rankings, err := GetRanking()
+func GetReviewRanking(ctx context.Context, admin_review string) (string, int, error) {
+	ctx, span := tracing.Tracer.Start(ctx, "GetReviewRanking")
+	defer span.End()
+	rankings, err := GetRanking(ctx)
 	if err != nil {
 		return "", 0, err
 	}
 	sentimentDelimited := ""
 	for _, ranking := range rankings {
-		if ranking.RankingValue != 999 {
+		if ranking.RankingValue != models.NotRanked.RankingValue {
 			sentimentDelimited = sentimentDelimited + ranking.RankingName + ","
 		}
 	}
 	sentimentDelimited = strings.Trim(sentimentDelimited, ",")
 	err = godotenv.Load(".env")
 	if err != nil {
-		log.Println("Warning: No .env file found")
+		logger.Warn("no .env file found")
 	}
 	OpenaiApiKey := os.Getenv("OPENAI_API_KEY")
 	if OpenaiApiKey == "" {
-		log.Fatal("OPENAI_API_KEY environment variable not set")
+		logger.Error("OPENAI_API_KEY environment variable not set")
+		os.Exit(1)
 	}
 	llm, err := openai.New()
 	if err != nil {
@@ -170,11 +314,18 @@ func GetReviewRanking(admin_review string) (string, int, error) {
 	}
 	base_prompt := os.Getenv("BASE_PROMPT_TEMPLATE")
 	if base_prompt == "" {
-		log.Fatal("BASE_PROMPT_TEMPLATE environment variable not set")
+		logger.Error("BASE_PROMPT_TEMPLATE environment variable not set")
+		os.Exit(1)
 	}
 	prompt := strings.Replace(base_prompt, "{rankings}", sentimentDelimited, 1)
-	response, err := llm.Call(context.Background(), prompt+admin_review)
+	span.SetAttributes(attribute.Int("llm.prompt_size", len(prompt)+len(admin_review)))
+	start := time.Now()
+	response, err := llm.Call(ctx, prompt+admin_review)
+	metrics.ObserveLLMCall(start, err)
 	if err != nil {
+		stats.RecordLLMCall(stats.LLMFailure)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "llm call failed")
 		return "", 0, err
 	}
 	rankVal := 0
@@ -184,22 +335,19 @@ func GetReviewRanking(admin_review string) (string, int, error) {
 			break
 		}
 	}
+	if rankVal == 0 {
+		stats.RecordLLMCall(stats.LLMUnmatched)
+	} else {
+		stats.RecordLLMCall(stats.LLMSuccess)
+	}
+	span.SetAttributes(attribute.String("ranking.label", response), attribute.Int("ranking.value", rankVal))
 	return response, rankVal, nil
 }
 
-func GetRanking() ([]models.Ranking, error) {
-	var rankings []models.Ranking
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+func GetRanking(ctx context.Context) ([]models.Ranking, error) {
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Second)
 	defer cancel()
-	cursor, err := rankingCollection.Find(ctx, bson.M{})
-	if err != nil {
-		return nil, err
-	}
-	defer cursor.Close(ctx)
-	if err = cursor.All(ctx, &rankings); err != nil {
-		return nil, err
-	}
-	return rankings, nil
+	return database.FindRankings(ctx)
 }
 
 func GetRecommendedMovies() gin.HandlerFunc {
@@ -209,14 +357,14 @@ func GetRecommendedMovies() gin.HandlerFunc {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 			return
 		}
-		favGenres, err := GetUserFavouriteGenres(userId)
+		favGenres, err := GetUserFavouriteGenres(c.Request.Context(), userId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
 		err = godotenv.Load(".env")
 		if err != nil {
-			log.Println("Warning: No .env file found")
+			logging.FromContext(c, logger).Warn("no .env file found")
 		}
 		var recommendedMovieLimitVal int64
 		recommendedMovieLimit := os.Getenv("RECOMMENDED_MOVIE_LIMIT")
@@ -226,9 +374,9 @@ func GetRecommendedMovies() gin.HandlerFunc {
 		findOptions := options.Find()
 		findOptions.SetSort(bson.D{{Key: "ranking.ranking_value", Value: 1}})
 		filter := bson.M{"genre.genre_name": bson.M{"$in": favGenres}}
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
-		cursor, err := movieCollection.Find(ctx, filter, findOptions.SetLimit(recommendedMovieLimitVal))
+		cursor, err := database.Movies.Find(ctx, filter, findOptions.SetLimit(recommendedMovieLimitVal))
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching recommended movies"})
 			return
@@ -243,8 +391,8 @@ func GetRecommendedMovies() gin.HandlerFunc {
 	}
 }
 
-func GetUserFavouriteGenres(userId string) ([]string, error) {
-	var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+func GetUserFavouriteGenres(ctx context.Context, userId string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Second)
 	defer cancel()
 	filter := bson.M{"user_id": userId}
 	projection := bson.M{
@@ -253,7 +401,7 @@ func GetUserFavouriteGenres(userId string) ([]string, error) {
 	}
 	opts := options.FindOne().SetProjection(projection)
 	var results bson.M
-	err := userCollection.FindOne(ctx, filter, opts).Decode(&results)
+	err := database.Users.FindOne(ctx, filter, opts).Decode(&results)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return []string{}, nil
@@ -281,19 +429,57 @@ func GetUserFavouriteGenres(userId string) ([]string, error) {
 
 func GetGenres() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
-		var genres []models.Genre
-		cursor, err := genreCollection.Find(ctx, bson.M{})
+		genres, err := database.FindGenres(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching genres from database"})
 			return
 		}
-		defer cursor.Close(ctx)
-		if err := cursor.All(ctx, &genres); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error decoding genres from database"})
+		c.JSON(http.StatusOK, genres)
+	}
+}
+
+func AddGenre() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
 			return
 		}
-		c.JSON(http.StatusOK, genres)
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can add genres"})
+			return
+		}
+		var genre models.Genre
+		if err := c.ShouldBindJSON(&genre); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid genre data"})
+			return
+		}
+		if err := validate.Struct(genre); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		count, err := database.Genres.CountDocuments(ctx, bson.M{"$or": bson.A{
+			bson.M{"genre_id": genre.GenreID},
+			bson.M{"genre_name": genre.GenreName},
+		}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking for existing genre"})
+			return
+		}
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Genre already exists"})
+			return
+		}
+		result, err := database.Genres.InsertOne(ctx, genre)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error inserting genre into database"})
+			return
+		}
+		invalidateCatalogue(c)
+		c.JSON(http.StatusCreated, result)
 	}
 }
@@ -0,0 +1,241 @@
+package controllers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"golang.org/x/oauth2"
+)
+
+const (
+	oidcFlowCookie = "oidc_flow"
+	oidcFlowTTL    = 10 * time.Minute
+)
+
+// oidcFlow carries the state, nonce and PKCE verifier between the redirect to
+// the provider and the callback, in a signed cookie so no server state is needed.
+type oidcFlow struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+type oidcClaims struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Name          string `json:"name"`
+}
+
+func OIDCLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := utils.GetOIDCProvider(c.Request.Context())
+		if errors.Is(err, utils.ErrOIDCNotConfigured) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "External login is not enabled"})
+			return
+		}
+		if err != nil {
+			logging.FromContext(c, logger).Error("error discovering OIDC provider", "error", err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable"})
+			return
+		}
+		state, _, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+			return
+		}
+		nonce, _, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+			return
+		}
+		verifier := oauth2.GenerateVerifier()
+		flow, err := utils.Keys.Sign(&oidcFlow{
+			State:    state,
+			Nonce:    nonce,
+			Verifier: verifier,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   "oidc-flow",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcFlowTTL)),
+			},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error starting login"})
+			return
+		}
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     oidcFlowCookie,
+			Value:    flow,
+			Path:     "/auth/oidc",
+			MaxAge:   int(oidcFlowTTL.Seconds()),
+			Secure:   true,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+		c.Redirect(http.StatusFound, provider.Config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier), oidc.Nonce(nonce)))
+	}
+}
+
+// OIDCCallback completes the authorization code flow, links or creates the
+// local user and issues the same session cookies as LoginUser.
+func OIDCCallback() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provider, err := utils.GetOIDCProvider(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "External login is not enabled"})
+			return
+		}
+		if errParam := c.Query("error"); errParam != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login was cancelled or refused", "details": errParam})
+			return
+		}
+		cookie, err := c.Cookie(oidcFlowCookie)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Login session not found, please try again"})
+			return
+		}
+		http.SetCookie(c.Writer, &http.Cookie{Name: oidcFlowCookie, Path: "/auth/oidc", MaxAge: -1, Secure: true, HttpOnly: true})
+		flow := &oidcFlow{}
+		_, err = jwt.ParseWithClaims(cookie, flow, utils.Keys.Keyfunc, append(utils.ParserOptions(), jwt.WithSubject("oidc-flow"))...)
+		if err != nil || subtle.ConstantTimeCompare([]byte(flow.State), []byte(c.Query("state"))) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid login state, please try again"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		oauthToken, err := provider.Config.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(flow.Verifier))
+		if err != nil {
+			logging.FromContext(c, logger).Warn("error exchanging OIDC code", "error", err)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Could not complete login with the identity provider"})
+			return
+		}
+		rawIDToken, ok := oauthToken.Extra("id_token").(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Identity provider did not return an ID token"})
+			return
+		}
+		idToken, err := provider.Verifier.Verify(ctx, rawIDToken)
+		if err != nil || subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(flow.Nonce)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+			return
+		}
+		var claims oidcClaims
+		if err := idToken.Claims(&claims); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token claims"})
+			return
+		}
+
+		user, err := findOrLinkExternalUser(ctx, provider.Name, idToken.Subject, claims)
+		if errors.Is(err, errUnverifiedExternalEmail) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The identity provider has not verified this email address"})
+			return
+		}
+		if err != nil {
+			logging.FromContext(c, logger).Error("error linking external identity", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error completing login"})
+			return
+		}
+		if user.MFAEnabled {
+			// The client's /login/mfa page finishes the login by posting a code
+			// to /login/mfa, which picks the challenge up from the cookie so it
+			// never appears in a URL.
+			mfaToken, err := utils.GenerateMFAChallenge(user.UserID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating MFA challenge"})
+				return
+			}
+			setMFAChallengeCookie(c, mfaToken)
+			c.Redirect(http.StatusFound, appURL("/login/mfa"))
+			return
+		}
+		token, refreshToken, err := createSession(ctx, *user, false)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating tokens"})
+			return
+		}
+		setSessionCookies(c, token, refreshToken)
+		redirect := os.Getenv("OIDC_POST_LOGIN_REDIRECT")
+		if redirect == "" {
+			redirect = appURL("/")
+		}
+		c.Redirect(http.StatusFound, redirect)
+	}
+}
+
+var errUnverifiedExternalEmail = errors.New("external email not verified")
+
+// findOrLinkExternalUser returns the user already linked to the external
+// identity, otherwise links the user with the same email, otherwise creates
+// one. Emails are only trusted when the provider has verified them.
+func findOrLinkExternalUser(ctx context.Context, providerName, subject string, claims oidcClaims) (*models.User, error) {
+	var user models.User
+	err := database.Users.FindOne(ctx, bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": providerName, "subject": subject}}}).Decode(&user)
+	if err == nil {
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, errUnverifiedExternalEmail
+	}
+
+	identity := models.ExternalIdentity{Provider: providerName, Subject: subject, LinkedAt: time.Now()}
+	err = database.Users.FindOneAndUpdate(ctx, bson.M{"email": claims.Email}, bson.M{
+		"$push": bson.M{"identities": identity},
+		"$set":  bson.M{"email_verified": true, "updated_at": time.Now()},
+	}).Decode(&user)
+	if err == nil {
+		user.EmailVerified = true
+		return &user, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// New users get an unguessable password; they can set one with the reset flow.
+	randomPassword, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := HashPassword(randomPassword[:32])
+	if err != nil {
+		return nil, err
+	}
+	firstName, lastName := claims.GivenName, claims.FamilyName
+	if firstName == "" {
+		firstName = claims.Name
+	}
+	user = models.User{
+		UserID:        bson.NewObjectID().Hex(),
+		FirstName:     firstName,
+		LastName:      lastName,
+		Email:         claims.Email,
+		Password:      hashedPassword,
+		Role:          "user",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		EmailVerified: true,
+		Identities:    []models.ExternalIdentity{identity},
+	}
+	if _, err := database.Users.InsertOne(ctx, user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
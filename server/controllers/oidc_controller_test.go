@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const testOIDCClientID = "magicstream-test"
+
+// mockIdP is a minimal OpenID provider serving discovery, JWKS and the token
+// endpoint. Tests skip the authorize step and register the code it would
+// have issued with grant.
+type mockIdP struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+	subject   string
+	email     string
+	verified  bool
+}
+
+func newMockIdP() (*mockIdP, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	idp := &mockIdP{key: key, grants: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                idp.URL,
+			"authorization_endpoint":                idp.URL + "/authorize",
+			"token_endpoint":                        idp.URL + "/token",
+			"jwks_uri":                              idp.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.Server = httptest.NewServer(mux)
+	return idp, nil
+}
+
+func (idp *mockIdP) grant(code string, g mockGrant) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.grants[code] = g
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	idp.mu.Lock()
+	g, ok := idp.grants[r.PostForm.Get("code")]
+	delete(idp.grants, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            idp.URL,
+		"aud":            testOIDCClientID,
+		"sub":            g.subject,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          g.nonce,
+		"email":          g.email,
+		"email_verified": g.verified,
+		"given_name":     "Test",
+		"family_name":    "User",
+	})
+	token.Header["kid"] = "test"
+	idToken, err := token.SignedString(idp.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+var testIdP *mockIdP
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	keyDir, err := os.MkdirTemp("", "keys")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("JWT_KEY_DIR", keyDir)
+	ring, err := utils.LoadKeyring()
+	if err != nil {
+		panic(err)
+	}
+	if _, err := ring.Generate(); err != nil {
+		panic(err)
+	}
+	if err := utils.InitKeyring(); err != nil {
+		panic(err)
+	}
+
+	// The provider is discovered once and cached, so every test shares the
+	// same mock.
+	testIdP, err = newMockIdP()
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("OIDC_ISSUER_URL", testIdP.URL)
+	os.Setenv("OIDC_CLIENT_ID", testOIDCClientID)
+	os.Setenv("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback")
+
+	code := m.Run()
+	testIdP.Close()
+	os.RemoveAll(keyDir)
+	os.Exit(code)
+}
+
+func oidcRouter() *gin.Engine {
+	router := gin.New()
+	router.GET("/auth/oidc/login", OIDCLogin())
+	router.GET("/auth/oidc/callback", OIDCCallback())
+	return router
+}
+
+// oidcLogin starts a login and returns the flow cookie along with the state,
+// nonce and PKCE challenge sent to the provider.
+func oidcLogin(t *testing.T, router *gin.Engine) (flow *http.Cookie, state, nonce, challenge string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login: got status %d: %s", w.Code, w.Body)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := location.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("login: code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcFlowCookie {
+			flow = cookie
+		}
+	}
+	if flow == nil {
+		t.Fatal("login: no flow cookie set")
+	}
+	return flow, query.Get("state"), query.Get("nonce"), query.Get("code_challenge")
+}
+
+func oidcCallback(router *gin.Engine, flow *http.Cookie, code, state string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+url.Values{"code": {code}, "state": {state}}.Encode(), nil)
+	if flow != nil {
+		req.AddCookie(flow)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// requireMongo skips tests that need the users collection unless MONGO_URI
+// names a reachable database.
+func requireMongo(t *testing.T) {
+	t.Helper()
+	if os.Getenv("MONGO_URI") == "" {
+		t.Skip("MONGO_URI is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := database.Client.Ping(ctx, nil); err != nil {
+		t.Skip("MongoDB is not reachable:", err)
+	}
+}
+
+func uniqueEmail() string {
+	return "oidc-" + bson.NewObjectID().Hex() + "@example.com"
+}
+
+// cleanUpUser deletes the user with email once the test ends.
+func cleanUpUser(t *testing.T, email string) {
+	t.Cleanup(func() {
+		_, _ = database.Users.DeleteOne(context.Background(), bson.M{"email": email})
+	})
+}
+
+func TestOIDCCallbackRejectsMissingFlowCookie(t *testing.T) {
+	router := oidcRouter()
+	_, state, _, _ := oidcLogin(t, router)
+	if w := oidcCallback(router, nil, "code", state); w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOIDCCallbackRejectsStateMismatch(t *testing.T) {
+	router := oidcRouter()
+	flow, _, nonce, challenge := oidcLogin(t, router)
+	testIdP.grant("state-mismatch", mockGrant{challenge: challenge, nonce: nonce, subject: "s1", email: uniqueEmail(), verified: true})
+	if w := oidcCallback(router, flow, "state-mismatch", "forged-state"); w.Code != http.StatusBadRequest {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestOIDCCallbackRejectsPKCEMismatch(t *testing.T) {
+	router := oidcRouter()
+	flow, state, nonce, _ := oidcLogin(t, router)
+	// The code was issued for another login's challenge, so the verifier in
+	// this flow cookie does not match it.
+	_, _, _, otherChallenge := oidcLogin(t, router)
+	testIdP.grant("pkce-mismatch", mockGrant{challenge: otherChallenge, nonce: nonce, subject: "s2", email: uniqueEmail(), verified: true})
+	w := oidcCallback(router, flow, "pkce-mismatch", state)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Could not complete login") {
+		t.Fatalf("got status %d, want %d from the code exchange: %s", w.Code, http.StatusUnauthorized, w.Body)
+	}
+}
+
+func TestOIDCCallbackRejectsNonceMismatch(t *testing.T) {
+	router := oidcRouter()
+	flow, state, _, challenge := oidcLogin(t, router)
+	testIdP.grant("nonce-mismatch", mockGrant{challenge: challenge, nonce: "replayed-nonce", subject: "s3", email: uniqueEmail(), verified: true})
+	w := oidcCallback(router, flow, "nonce-mismatch", state)
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "Invalid ID token") {
+		t.Fatalf("got status %d, want %d from ID token verification: %s", w.Code, http.StatusUnauthorized, w.Body)
+	}
+}
+
+func TestOIDCCallbackCreatesUser(t *testing.T) {
+	requireMongo(t)
+	router := oidcRouter()
+	email := uniqueEmail()
+	cleanUpUser(t, email)
+	flow, state, nonce, challenge := oidcLogin(t, router)
+	testIdP.grant("new-user", mockGrant{challenge: challenge, nonce: nonce, subject: "new-subject", email: email, verified: true})
+
+	w := oidcCallback(router, flow, "new-user", state)
+	if w.Code != http.StatusFound {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusFound, w.Body)
+	}
+	var sessionSet bool
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "access_token" && cookie.Value != "" {
+			sessionSet = true
+		}
+	}
+	if !sessionSet {
+		t.Fatal("no session cookie set")
+	}
+	user, err := database.FindUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.EmailVerified || len(user.Identities) != 1 || user.Identities[0].Subject != "new-subject" {
+		t.Fatalf("user not created with the external identity: %+v", user)
+	}
+}
+
+func TestOIDCCallbackLinksVerifiedEmail(t *testing.T) {
+	requireMongo(t)
+	router := oidcRouter()
+	email := uniqueEmail()
+	cleanUpUser(t, email)
+	existing := models.User{UserID: bson.NewObjectID().Hex(), FirstName: "Existing", LastName: "User", Email: email, Role: "user"}
+	if _, err := database.Users.InsertOne(context.Background(), existing); err != nil {
+		t.Fatal(err)
+	}
+	flow, state, nonce, challenge := oidcLogin(t, router)
+	testIdP.grant("link", mockGrant{challenge: challenge, nonce: nonce, subject: "link-subject", email: email, verified: true})
+
+	if w := oidcCallback(router, flow, "link", state); w.Code != http.StatusFound {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusFound, w.Body)
+	}
+	user, err := database.FindUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UserID != existing.UserID || len(user.Identities) != 1 || user.Identities[0].Subject != "link-subject" {
+		t.Fatalf("identity not linked to the existing user: %+v", user)
+	}
+}
+
+func TestOIDCCallbackRefusesUnverifiedEmail(t *testing.T) {
+	requireMongo(t)
+	router := oidcRouter()
+	email := uniqueEmail()
+	cleanUpUser(t, email)
+	existing := models.User{UserID: bson.NewObjectID().Hex(), FirstName: "Existing", LastName: "User", Email: email, Role: "user"}
+	if _, err := database.Users.InsertOne(context.Background(), existing); err != nil {
+		t.Fatal(err)
+	}
+	flow, state, nonce, challenge := oidcLogin(t, router)
+	testIdP.grant("unverified", mockGrant{challenge: challenge, nonce: nonce, subject: "unverified-subject", email: email, verified: false})
+
+	if w := oidcCallback(router, flow, "unverified", state); w.Code != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusForbidden, w.Body)
+	}
+	user, err := database.FindUserByEmail(context.Background(), email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(user.Identities) != 0 {
+		t.Fatalf("identity linked without a verified email: %+v", user.Identities)
+	}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/mailer"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const passwordResetTTL = time.Hour
+
+var passwordPolicy = loadPasswordPolicy()
+var mail = loadMailer()
+
+func loadPasswordPolicy() *utils.PasswordPolicy {
+	policy, err := utils.LoadPasswordPolicy()
+	if err != nil {
+		logger.Error("error loading password policy", "error", err)
+		os.Exit(1)
+	}
+	return policy
+}
+
+func loadMailer() mailer.Mailer {
+	m, err := mailer.FromEnv()
+	if err != nil {
+		logger.Error("error configuring mailer", "error", err)
+		os.Exit(1)
+	}
+	return m
+}
+
+// appURL builds a link into the React client.
+func appURL(path string) string {
+	base := os.Getenv("APP_BASE_URL")
+	if base == "" {
+		base = "http://localhost:5173"
+	}
+	return base + path
+}
+
+// ForgotPassword always answers 202 so it cannot be used to discover which
+// emails are registered.
+func ForgotPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ForgotPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+		accepted := gin.H{"message": "If an account exists for this email, a reset link has been sent"}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+
+		var user models.User
+		err := database.Users.FindOne(ctx, bson.M{"email": req.Email}).Decode(&user)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusAccepted, accepted)
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error looking up user"})
+			return
+		}
+
+		token, tokenHash, err := utils.GenerateOpaqueToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating reset token"})
+			return
+		}
+		// Only the most recently requested link stays valid.
+		if _, err := database.PasswordResets.DeleteMany(ctx, bson.M{"user_id": user.UserID, "used_at": bson.M{"$exists": false}}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing reset token"})
+			return
+		}
+		reset := models.PasswordReset{
+			TokenHash: tokenHash,
+			UserID:    user.UserID,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+			CreatedAt: time.Now(),
+		}
+		if _, err := database.PasswordResets.InsertOne(ctx, reset); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error storing reset token"})
+			return
+		}
+		err = mail.Send(ctx, mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your MagicStream password",
+			Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n",
+				user.FirstName, passwordResetTTL, appURL("/reset-password?token="+token)),
+		})
+		if err != nil {
+			logging.FromContext(c, logger).Error("error sending password reset email", "error", err)
+		}
+		c.JSON(http.StatusAccepted, accepted)
+	}
+}
+
+func ResetPassword() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req models.ResetPasswordRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "details": err.Error()})
+			return
+		}
+		if err := passwordPolicy.Validate(req.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hashedPassword, err := HashPassword(req.Password)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error hashing password"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+
+		// Marking the token used in the same operation that finds it keeps it single-use.
+		now := time.Now()
+		var reset models.PasswordReset
+		err = database.PasswordResets.FindOneAndUpdate(ctx, bson.M{
+			"token_hash": utils.HashOpaqueToken(req.Token),
+			"used_at":    bson.M{"$exists": false},
+			"expires_at": bson.M{"$gt": now},
+		}, bson.M{"$set": bson.M{"used_at": now}}).Decode(&reset)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reset link is invalid or has expired"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error validating reset token"})
+			return
+		}
+
+		// Existing sessions are ended and any lockout is lifted. Bumping the
+		// session version is what stops refresh tokens issued before the reset
+		// from minting new ones.
+		result, err := database.Users.UpdateOne(ctx, bson.M{"user_id": reset.UserID}, bson.M{
+			"$set": bson.M{
+				"password":              hashedPassword,
+				"token":                 "",
+				"refresh_token":         "",
+				"failed_login_attempts": 0,
+				"updated_at":            now,
+			},
+			"$inc":   bson.M{"session_version": 1},
+			"$unset": bson.M{"locked_until": ""},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating password"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func postJSON(router *gin.Engine, path string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestPasswordResetEndsRefreshTokens(t *testing.T) {
+	requireMongo(t)
+	ctx := context.Background()
+	user := models.User{
+		UserID:        bson.NewObjectID().Hex(),
+		FirstName:     "Reset",
+		LastName:      "Test",
+		Email:         uniqueEmail(),
+		Role:          "user",
+		EmailVerified: true,
+	}
+	cleanUpUser(t, user.Email)
+	if _, err := database.Users.InsertOne(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = database.PasswordResets.DeleteMany(context.Background(), bson.M{"user_id": user.UserID})
+	})
+	_, refreshToken, err := createSession(ctx, user, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.POST("/refresh", RefreshTokenHandler())
+	router.POST("/password/reset", ResetPassword())
+
+	// The token works until the reset, which rules out it failing for some
+	// other reason afterwards.
+	w := postJSON(router, "/refresh", gin.H{"refresh_token": refreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("refresh before reset: got status %d: %s", w.Code, w.Body)
+	}
+	var refreshed struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &refreshed); err != nil {
+		t.Fatal(err)
+	}
+
+	resetToken := bson.NewObjectID().Hex()
+	_, err = database.PasswordResets.InsertOne(ctx, models.PasswordReset{
+		TokenHash: utils.HashOpaqueToken(resetToken),
+		UserID:    user.UserID,
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = postJSON(router, "/password/reset", gin.H{"token": resetToken, "password": "Quiet-Harbour-" + bson.NewObjectID().Hex()})
+	if w.Code != http.StatusOK {
+		t.Fatalf("reset: got status %d: %s", w.Code, w.Body)
+	}
+
+	for name, token := range map[string]string{"original": refreshToken, "refreshed": refreshed.RefreshToken} {
+		if w := postJSON(router, "/refresh", gin.H{"refresh_token": token}); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s refresh token after reset: got status %d, want %d", name, w.Code, http.StatusUnauthorized)
+		}
+	}
+}
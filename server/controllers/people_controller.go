@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	peopleSearchLimit   = 50
+	moreFromDirectorMax = 10
+)
+
+var summaryProjection = bson.M{"imdb_id": 1, "title": 1, "poster_path": 1, "release_date": 1}
+
+// SearchPeople lists people whose name contains the search parameter,
+// alphabetically and at most 50 at a time.
+func SearchPeople() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		filter := bson.M{}
+		if search := strings.TrimSpace(c.Query("search")); search != "" {
+			filter["name_key"] = bson.M{"$regex": regexp.QuoteMeta(strings.ToLower(search))}
+		}
+		opts := options.Find().SetSort(bson.D{{Key: "name_key", Value: 1}}).SetLimit(peopleSearchLimit)
+		people, err := database.FindAll[models.Person](ctx, database.People, filter, opts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching people from database"})
+			return
+		}
+		c.JSON(http.StatusOK, people)
+	}
+}
+
+// GetPerson returns a person with every movie they are credited on, oldest
+// first. Movies without a release date are listed last.
+func GetPerson() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		personId := c.Param("person_id")
+		person, err := database.FindPersonByID(ctx, personId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Person not found"})
+			return
+		}
+		movies, err := database.FindAll[models.Movie](ctx, database.Movies, bson.M{"$or": bson.A{
+			bson.M{"cast.person_id": personId},
+			bson.M{"crew.person_id": personId},
+		}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching filmography"})
+			return
+		}
+		filmography := make([]models.FilmographyEntry, 0, len(movies))
+		for _, movie := range movies {
+			entry := models.FilmographyEntry{MovieSummary: models.MovieSummary{
+				ImdbID:      movie.ImdbID,
+				Title:       movie.Title,
+				PosterPath:  movie.PosterPath,
+				ReleaseDate: movie.ReleaseDate,
+			}}
+			for _, credit := range movie.Cast {
+				if credit.PersonID == personId && credit.Character != "" {
+					entry.Characters = append(entry.Characters, credit.Character)
+				}
+			}
+			for _, credit := range movie.Crew {
+				if credit.PersonID == personId {
+					entry.Jobs = append(entry.Jobs, credit.Job)
+				}
+			}
+			filmography = append(filmography, entry)
+		}
+		sort.SliceStable(filmography, func(i, j int) bool {
+			a, b := filmography[i].ReleaseDate, filmography[j].ReleaseDate
+			if a == "" || b == "" {
+				return b == "" && a != ""
+			}
+			return a < b
+		})
+		c.JSON(http.StatusOK, models.PersonResponse{Person: *person, Filmography: filmography})
+	}
+}
+
+// moreFromDirector lists the other movies directed by movie's directors,
+// newest first.
+func moreFromDirector(ctx context.Context, movie *models.Movie) ([]models.MovieSummary, error) {
+	var directors []string
+	for _, credit := range movie.Crew {
+		if credit.Job == "Director" && credit.PersonID != "" {
+			directors = append(directors, credit.PersonID)
+		}
+	}
+	if len(directors) == 0 {
+		return []models.MovieSummary{}, nil
+	}
+	filter := bson.M{
+		"imdb_id": bson.M{"$ne": movie.ImdbID},
+		"crew":    bson.M{"$elemMatch": bson.M{"person_id": bson.M{"$in": directors}, "job": "Director"}},
+	}
+	opts := options.Find().
+		SetProjection(summaryProjection).
+		SetSort(bson.D{{Key: "release_date", Value: -1}}).
+		SetLimit(moreFromDirectorMax)
+	return database.FindAll[models.MovieSummary](ctx, database.Movies, filter, opts)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"os"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/cache"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// catalogueCachePrefix namespaces the cached movie and genre responses,
+// which are all dropped together whenever the catalogue changes.
+const catalogueCachePrefix = "catalogue:"
+
+// responseCache holds catalogue responses, which every user shares.
+// CACHE_MAX_ENTRIES and CACHE_MAX_BYTES size it.
+var responseCache = loadCache("CACHE", 1000, 64<<20)
+
+var cataloguePolicy = loadCataloguePolicy()
+
+func loadCache(prefix string, maxEntries, maxBytes int) cache.Cache {
+	store, err := cache.FromEnv(prefix, maxEntries, maxBytes)
+	if err != nil {
+		logger.Error("error configuring cache", "prefix", prefix, "error", err)
+		os.Exit(1)
+	}
+	return store
+}
+
+// loadCataloguePolicy reads CACHE_TTL, how long the server keeps catalogue
+// responses, and CACHE_MAX_AGE, how long clients may reuse them.
+func loadCataloguePolicy() middleware.CachePolicy {
+	policy := middleware.CachePolicy{TTL: 5 * time.Minute, MaxAge: time.Minute}
+	if d, err := time.ParseDuration(os.Getenv("CACHE_TTL")); err == nil && d > 0 {
+		policy.TTL = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("CACHE_MAX_AGE")); err == nil && d >= 0 {
+		policy.MaxAge = d
+	}
+	return policy
+}
+
+// CacheCatalogue caches a catalogue read. Responses to authenticated
+// routes are marked private so shared proxies do not keep them. Streamed
+// responses are passed straight through, since caching them would mean
+// buffering the whole body.
+func CacheCatalogue(private bool) gin.HandlerFunc {
+	policy := cataloguePolicy
+	policy.Private = private
+	cached := middleware.CacheResponse(responseCache, catalogueCachePrefix, policy)
+	return func(c *gin.Context) {
+		if c.Query("stream") != "" {
+			c.Next()
+			return
+		}
+		cached(c)
+	}
+}
+
+// invalidateCatalogue drops every cached catalogue response. Call it after
+// any write to movies or genres.
+func invalidateCatalogue(c *gin.Context) {
+	if err := responseCache.DeletePrefix(c.Request.Context(), catalogueCachePrefix); err != nil {
+		logging.FromContext(c, logger).Warn("error invalidating catalogue cache", "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/middleware"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// issueSession creates a new token pair for user and completes the login.
+// Browsers get the tokens as cookies; API and CLI clients that ask for them
+// get them in the response body instead.
+func issueSession(c *gin.Context, ctx context.Context, user models.User, returnTokens, mfaVerified bool) {
+	token, refreshToken, err := createSession(ctx, user, mfaVerified)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating tokens"})
+		return
+	}
+	response := models.UserResponse{
+		UserID:          user.UserID,
+		FirstName:       user.FirstName,
+		LastName:        user.LastName,
+		Email:           user.Email,
+		Role:            user.Role,
+		FavouriteGenres: user.FavouriteGenres,
+		EmailVerified:   user.EmailVerified,
+		MFAEnabled:      user.MFAEnabled,
+	}
+	if returnTokens {
+		response.Token = token
+		response.RefreshToken = refreshToken
+	} else {
+		setSessionCookies(c, token, refreshToken)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// createSession generates a token pair for user and records it as the user's
+// current session.
+func createSession(ctx context.Context, user models.User, mfaVerified bool) (string, string, error) {
+	token, refreshToken, err := utils.GenerateToken(user.Email, user.FirstName, user.LastName, user.Role, user.UserID, user.EmailVerified, mfaVerified, user.SessionVersion)
+	if err != nil {
+		return "", "", err
+	}
+	if err = utils.UpdateAllTokens(ctx, user.UserID, token, refreshToken); err != nil {
+		return "", "", err
+	}
+	return token, refreshToken, nil
+}
+
+// setSessionCookies sets the auth cookies along with a fresh CSRF token that
+// the client has to echo back in the X-CSRF-Token header.
+func setSessionCookies(c *gin.Context, token, refreshToken string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "access_token",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   86400,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     "refresh_token",
+		Value:    refreshToken,
+		Path:     "/",
+		MaxAge:   604800,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	csrfToken, _, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return
+	}
+	// Readable by scripts on purpose: the double-submit check relies on the
+	// client copying it into a header, which another origin cannot do.
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     middleware.CSRFCookie,
+		Value:    csrfToken,
+		Path:     "/",
+		MaxAge:   604800,
+		Secure:   true,
+		HttpOnly: false,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// mfaChallengeCookie carries the MFA challenge from an external login to the
+// /login/mfa request, scoped to that path only.
+const mfaChallengeCookie = "mfa_challenge"
+
+func setMFAChallengeCookie(c *gin.Context, mfaToken string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     mfaChallengeCookie,
+		Value:    mfaToken,
+		Path:     "/login/mfa",
+		MaxAge:   int(utils.MFAChallengeTTL.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func clearMFAChallengeCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{Name: mfaChallengeCookie, Path: "/login/mfa", MaxAge: -1, Secure: true, HttpOnly: true})
+}
+
+func clearSessionCookies(c *gin.Context) {
+	for _, name := range []string{"access_token", "refresh_token", middleware.CSRFCookie} {
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     name,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			Secure:   true,
+			HttpOnly: name != middleware.CSRFCookie,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
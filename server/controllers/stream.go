@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Streaming response formats, chosen with the stream query parameter.
+const (
+	streamNDJSON = "ndjson"
+	streamJSON   = "json"
+)
+
+var streamContentTypes = map[string]string{
+	streamNDJSON: "application/x-ndjson",
+	streamJSON:   "application/json; charset=utf-8",
+}
+
+// streamErrorTrailer is sent as an HTTP trailer when a streamed body is cut
+// short after the 200 status has gone out. A JSON array is also left
+// unclosed so clients that ignore trailers still fail to parse it.
+const streamErrorTrailer = "X-Stream-Error"
+
+const (
+	// streamTimeout bounds a whole streamed response. It is far longer than
+	// the usual request timeout because large listings to slow clients take
+	// a while; a client that goes away cancels the stream sooner.
+	streamTimeout = time.Hour
+	// streamBatchSize caps how many documents the driver holds at a time.
+	streamBatchSize = 500
+	// streamFlushEvery is how many documents are written between flushes.
+	streamFlushEvery = 100
+)
+
+// streamFormat reads the stream query parameter. An empty format means the
+// caller wants an ordinary response.
+func streamFormat(c *gin.Context) (string, error) {
+	format := c.Query("stream")
+	if _, ok := streamContentTypes[format]; format != "" && !ok {
+		return "", fmt.Errorf("stream must be %s or %s", streamNDJSON, streamJSON)
+	}
+	return format, nil
+}
+
+// streamCursor writes every document of cursor to the response as it is
+// read. Only one document is decoded at a time, and writes block while the
+// client is slow to read, so the cursor never runs ahead of the client.
+func streamCursor[T any](ctx context.Context, c *gin.Context, cursor *mongo.Cursor, format string) {
+	defer cursor.Close(ctx)
+	c.Header("Content-Type", streamContentTypes[format])
+	c.Header("Trailer", streamErrorTrailer)
+	c.Status(http.StatusOK)
+	err := writeCursor[T](ctx, c.Writer, cursor, format)
+	switch {
+	case err == nil:
+	case !c.Writer.Written():
+		// Nothing has been sent, so the headers can still be replaced.
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Trailer")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading from database"})
+	default:
+		logging.FromContext(c, logger).Error("stream interrupted", "path", c.FullPath(), "error", err)
+		c.Writer.Header().Set(streamErrorTrailer, "Error reading from database")
+		c.Abort()
+	}
+}
+
+func writeCursor[T any](ctx context.Context, w gin.ResponseWriter, cursor *mongo.Cursor, format string) error {
+	encoder := json.NewEncoder(w)
+	written := 0
+	for cursor.Next(ctx) {
+		var item T
+		if err := cursor.Decode(&item); err != nil {
+			return err
+		}
+		switch {
+		case format == streamNDJSON:
+		case written == 0:
+			if _, err := io.WriteString(w, "["); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+		written++
+		if written%streamFlushEvery == 0 {
+			w.Flush()
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if format == streamJSON {
+		closing := "]\n"
+		if written == 0 {
+			closing = "[]\n"
+		}
+		if _, err := io.WriteString(w, closing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/stats"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	defaultTrendingLimit = 20
+	maxTrendingLimit     = 100
+)
+
+// trendingParams reads the window and limit query parameters and writes
+// the error response itself when they are invalid.
+func trendingParams(c *gin.Context, defaultWindow string) (string, int, bool) {
+	window := c.DefaultQuery("window", defaultWindow)
+	if _, ok := stats.Windows[window]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window must be 24h or 7d"})
+		return "", 0, false
+	}
+	limit := defaultTrendingLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTrendingLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return "", 0, false
+		}
+		limit = n
+	}
+	return window, limit, true
+}
+
+// respondWithTrending writes the trending entries joined with their movies.
+func respondWithTrending(c *gin.Context, ctx context.Context, window string, genreId *int, limit int) {
+	entries, computedAt, err := stats.Trending(ctx, window, genreId, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching trending movies"})
+		return
+	}
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ImdbID)
+	}
+	movies, err := moviesInOrder(ctx, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching trending movies"})
+		return
+	}
+	byID := make(map[string]models.TrendingEntry, len(entries))
+	for _, entry := range entries {
+		byID[entry.ImdbID] = entry
+	}
+	trending := make([]models.TrendingMovie, 0, len(movies))
+	for _, movie := range movies {
+		trending = append(trending, models.TrendingMovie{Movie: movie, TrendingEntry: byID[movie.ImdbID]})
+	}
+	c.JSON(http.StatusOK, models.TrendingResponse{Window: window, ComputedAt: computedAt, Movies: trending})
+}
+
+// GetTrendingMovies returns the most popular movies over the last 24 hours
+// or 7 days, as last computed by the stats aggregator.
+func GetTrendingMovies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		window, limit, ok := trendingParams(c, "24h")
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		respondWithTrending(c, ctx, window, nil, limit)
+	}
+}
+
+// GetGenreTopMovies returns the most popular movies in a genre, over the
+// last 7 days unless another window is asked for.
+func GetGenreTopMovies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		genreId, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Genre ID must be a number"})
+			return
+		}
+		window, limit, ok := trendingParams(c, "7d")
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		count, err := database.Genres.CountDocuments(ctx, bson.M{"genre_id": genreId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching genre"})
+			return
+		}
+		if count == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Genre not found"})
+			return
+		}
+		respondWithTrending(c, ctx, window, &genreId, limit)
+	}
+}
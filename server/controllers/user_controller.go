@@ -2,11 +2,14 @@ package controllers
 
 import (
 	"context"
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
 	"github.com/gin-gonic/gin"
@@ -16,8 +19,6 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection("users")
-
 func HashPassword(password string) (string, error) {
 	HashPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -35,11 +36,17 @@ func RegisterUser() gin.HandlerFunc {
 			return
 		}
 
+		// The ID is server-assigned but required by the model, so set it before validating.
+		user.UserID = bson.NewObjectID().Hex()
 		validate := validator.New()
 		if err = validate.Struct(user); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", " details": err.Error()})
 			return
 		}
+		if err = passwordPolicy.Validate(user.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		hashedPassword, err := HashPassword(user.Password)
 		if err != nil {
@@ -47,10 +54,10 @@ func RegisterUser() gin.HandlerFunc {
 			return
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
 
-		count, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
+		count, err := database.Users.CountDocuments(ctx, bson.M{"email": user.Email})
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error checking for existing user"})
 			return
@@ -60,16 +67,24 @@ func RegisterUser() gin.HandlerFunc {
 			return
 		}
 
-		user.UserID = bson.NewObjectID().Hex()
 		user.Password = hashedPassword
 		user.CreatedAt = time.Now()
 		user.UpdatedAt = time.Now()
+		user.EmailVerified = false
 
-		result, err := userCollection.InsertOne(ctx, user)
+		result, err := database.Users.InsertOne(ctx, user)
+		if mongo.IsDuplicateKeyError(err) {
+			// Lost a race with a concurrent registration for the same email.
+			c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error inserting user into database"})
 			return
 		}
+		if err = sendVerificationEmail(ctx, user); err != nil {
+			logging.FromContext(c, logger).Error("error sending verification email", "error", err)
+		}
 		c.JSON(http.StatusCreated, result)
 	}
 }
@@ -79,109 +94,100 @@ func LoginUser() gin.HandlerFunc {
 		var userLogin models.UserLogin
 		err := c.BindJSON(&userLogin)
 		if err != nil {
+			metrics.LoginFailures.WithLabelValues("invalid_payload").Inc()
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 			return
 		}
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
 		var foundUser models.User
-		err = userCollection.FindOne(ctx, bson.M{"email": userLogin.Email}).Decode(&foundUser)
+		err = database.Users.FindOne(ctx, bson.M{"email": userLogin.Email}).Decode(&foundUser)
 		if err != nil {
+			metrics.LoginFailures.WithLabelValues("unknown_email").Inc()
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
 		}
+		// A wrong password gets the same answer whether or not the account is
+		// locked, so failing a few logins does not show that an email is
+		// registered. Guesses made during a lock still count towards the next,
+		// longer one and are held back by the per-email rate limit.
 		err = bcrypt.CompareHashAndPassword([]byte(foundUser.Password), []byte(userLogin.Password))
 		if err != nil {
+			metrics.LoginFailures.WithLabelValues("bad_password").Inc()
+			if err := recordFailedLogin(ctx, foundUser.UserID); err != nil {
+				logging.FromContext(c, logger).Error("error recording failed login", "error", err)
+			}
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
 		}
-		token, refreshToken, err := utils.GenerateToken(foundUser.Email, foundUser.FirstName, foundUser.LastName, foundUser.Role, foundUser.UserID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating tokens"})
+		if retryAfter := time.Until(foundUser.LockedUntil); retryAfter > 0 {
+			metrics.LoginFailures.WithLabelValues("locked").Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusLocked, gin.H{"error": "Account is temporarily locked due to too many failed login attempts"})
 			return
 		}
-		if err = utils.UpdateAllTokens(foundUser.UserID, token, refreshToken); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tokens"})
+		if !foundUser.EmailVerified && utils.RequireEmailVerification() {
+			metrics.LoginFailures.WithLabelValues("unverified").Inc()
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address before logging in"})
 			return
 		}
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "access_token",
-			Value:    token,
-			Path:   "/",
-			MaxAge: 86400,
-			Secure: true,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-		})
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    token,
-			Path:   "/",
-			MaxAge: 604800,
-			Secure: true,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-		})
-
-		c.JSON(http.StatusOK, models.UserResponse{
-			UserID:          foundUser.UserID,
-			FirstName:       foundUser.FirstName,
-			LastName:        foundUser.LastName,
-			Email:           foundUser.Email,
-			Role:            foundUser.Role,
-			//Token:           token,
-			//RefreshToken:    refreshToken,
-			FavouriteGenres: foundUser.FavouriteGenres,
-		})
+		// With two-factor authentication the failure count is only reset once
+		// the code is right too, otherwise logging in again with the password
+		// would wipe out the failed code attempts.
+		if foundUser.MFAEnabled {
+			mfaToken, err := utils.GenerateMFAChallenge(foundUser.UserID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating MFA challenge"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"mfa_required": true, "mfa_token": mfaToken})
+			return
+		}
+		clearFailedLogins(c, ctx, foundUser)
+		issueSession(c, ctx, foundUser, userLogin.ReturnTokens, false)
 	}
 }
 
-func LogoutHandler() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var UserLogout struct{
+func LogoutHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var UserLogout struct {
 			UserID string `json:"user_id"`
 		}
 		err := c.ShouldBindJSON(&UserLogout)
-		if err != nil{
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
 			return
 		}
-		fmt.Println("user id from logout handler:", UserLogout.UserID)
-		err = utils.UpdateAllTokens(UserLogout.UserID, "", "")
-		if err != nil{
+		logging.FromContext(c, logger).Debug("logging out user", "logoutUserId", UserLogout.UserID)
+		err = utils.UpdateAllTokens(c.Request.Context(), UserLogout.UserID, "", "")
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error logging out user"})
 			return
 		}
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "access_token",
-			Value:    "",
-			Path:  "/",
-			MaxAge: -1,
-			Secure: true,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-		})
-		http.SetCookie(c.Writer, &http.Cookie{
-			Name:     "refresh_token",
-			Value:    "",
-			Path:  "/",
-			MaxAge: -1,
-			Secure: true,
-			HttpOnly: true,
-			SameSite: http.SameSiteLaxMode,
-		})
+		clearSessionCookies(c)
 		c.JSON(http.StatusOK, gin.H{"message": "User logged out successfully"})
 	}
 }
 
-func RefreshTokenHandler() gin.HandlerFunc{
-	return func(c *gin.Context){
-		var ctx, cancel = context.WithTimeout(context.Background(), 100*time.Second)
+func RefreshTokenHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ctx, cancel = context.WithTimeout(c.Request.Context(), 100*time.Second)
 		defer cancel()
-		refreshToken, err := c.Cookie("refresh_token")
-		if err != nil{
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token not found"})
-			return
+		// Non-browser clients send the refresh token in the body and get the
+		// new pair back the same way; browsers use the cookie.
+		var req struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+		_ = c.ShouldBindJSON(&req)
+		refreshToken := req.RefreshToken
+		returnTokens := refreshToken != ""
+		if !returnTokens {
+			cookie, err := c.Cookie("refresh_token")
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token not found"})
+				return
+			}
+			refreshToken = cookie
 		}
 		claim, err := utils.ValidateRefreshToken(refreshToken)
 		if err != nil || claim == nil {
@@ -190,19 +196,66 @@ func RefreshTokenHandler() gin.HandlerFunc{
 		}
 
 		var user models.User
-		err = userCollection.FindOne(ctx, bson.D{{Key:"user_id", Value: claim.UserID}}).Decode(&user)
-		if err != nil{
+		err = database.Users.FindOne(ctx, bson.D{{Key: "user_id", Value: claim.UserID}}).Decode(&user)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
 			return
 		}
-		newToken, newRefreshToken, _ := utils.GenerateToken(user.Email, user.FirstName, user.LastName, user.Role, user.UserID)
-		err = utils.UpdateAllTokens(user.UserID, newToken, newRefreshToken)
-		if err != nil{
+		// A password reset bumps the version, so a refresh token that leaked
+		// before it cannot keep the session alive.
+		if claim.SessionVersion != user.SessionVersion {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has ended, please log in again"})
+			return
+		}
+		newToken, newRefreshToken, err := utils.GenerateToken(user.Email, user.FirstName, user.LastName, user.Role, user.UserID, user.EmailVerified, claim.MFAVerified, user.SessionVersion)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error generating tokens"})
+			return
+		}
+		err = utils.UpdateAllTokens(ctx, user.UserID, newToken, newRefreshToken)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error updating tokens"})
 			return
 		}
-		c.SetCookie("access_token", newToken, 86400, "/", "", true, true)
-		c.SetCookie("refresh_token", newRefreshToken, 604800, "/", "", true, true)
+		if returnTokens {
+			c.JSON(http.StatusOK, gin.H{"token": newToken, "refresh_token": newRefreshToken})
+			return
+		}
+		setSessionCookies(c, newToken, newRefreshToken)
 		c.JSON(http.StatusOK, gin.H{"message": "Tokens refreshed successfully"})
-	}	
-}
\ No newline at end of file
+	}
+}
+
+func UnlockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, err := utils.GetRoleFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Role not found in context"})
+			return
+		}
+		if role != "admin" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can unlock users"})
+			return
+		}
+		userId := c.Param("user_id")
+		if userId == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		result, err := database.Users.UpdateOne(ctx, bson.M{"user_id": userId}, bson.M{
+			"$set":   bson.M{"failed_login_attempts": 0},
+			"$unset": bson.M{"locked_until": ""},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error unlocking user"})
+			return
+		}
+		if result.MatchedCount == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+	}
+}
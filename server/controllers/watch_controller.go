@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/stats"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// A movie counts as watched once 90% of its runtime has been played, so the
+// credits do not keep it on the continue watching rail.
+const completedFraction = 0.9
+
+// UpdateWatchProgress records where the caller stopped watching a movie.
+func UpdateWatchProgress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		var req models.WatchProgressRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+		if err := validate.Struct(req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		movie, err := database.FindMovieByImdbID(ctx, c.Param("imdb_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found"})
+			return
+		}
+		progress := models.WatchProgress{
+			UserID:          userId,
+			ImdbID:          movie.ImdbID,
+			PositionSeconds: req.PositionSeconds,
+			Completed:       req.Completed,
+			UpdatedAt:       time.Now(),
+		}
+		if runtime := movie.RuntimeMinutes * 60; runtime > 0 && float64(req.PositionSeconds) >= completedFraction*float64(runtime) {
+			progress.Completed = true
+		}
+		var previous models.WatchProgress
+		err = database.WatchProgress.FindOneAndReplace(ctx,
+			bson.M{"user_id": userId, "imdb_id": movie.ImdbID},
+			progress,
+			options.FindOneAndReplace().SetUpsert(true).SetReturnDocument(options.Before),
+		).Decode(&previous)
+		started := err == mongo.ErrNoDocuments
+		if err != nil && !started {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error saving watch progress"})
+			return
+		}
+		// Starting again after finishing counts as another play.
+		if started || (previous.Completed && !progress.Completed) {
+			stats.Record(movie.ImdbID, stats.EventPlay)
+		}
+		if progress.Completed && (started || !previous.Completed) {
+			stats.Record(movie.ImdbID, stats.EventCompletion)
+		}
+		invalidateFeed(c, userId)
+		c.JSON(http.StatusOK, progress)
+	}
+}
+
+// GetWatchProgress returns where the caller stopped watching a movie, or a
+// zero position if they have not started it.
+func GetWatchProgress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userId, err := utils.GetUserIdFromContext(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 100*time.Second)
+		defer cancel()
+		imdbId := c.Param("imdb_id")
+		var progress models.WatchProgress
+		err = database.WatchProgress.FindOne(ctx, bson.M{"user_id": userId, "imdb_id": imdbId}).Decode(&progress)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusOK, models.WatchProgress{ImdbID: imdbId})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching watch progress"})
+			return
+		}
+		c.JSON(http.StatusOK, progress)
+	}
+}
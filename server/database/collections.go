@@ -0,0 +1,24 @@
+package database
+
+import "go.mongodb.org/mongo-driver/v2/mongo"
+
+// Every collection the server uses is declared here, once.
+var (
+	Users              *mongo.Collection = OpenCollection("users")
+	Movies             *mongo.Collection = OpenCollection("movies")
+	Genres             *mongo.Collection = OpenCollection("genres")
+	Rankings           *mongo.Collection = OpenCollection("rankings")
+	PasswordResets     *mongo.Collection = OpenCollection("password_resets")
+	APIKeys            *mongo.Collection = OpenCollection("api_keys")
+	People             *mongo.Collection = OpenCollection("people")
+	CuratedCollections *mongo.Collection = OpenCollection("curated_collections")
+	UserLists          *mongo.Collection = OpenCollection("user_lists")
+	ListFollows        *mongo.Collection = OpenCollection("list_follows")
+	WatchProgress      *mongo.Collection = OpenCollection("watch_progress")
+	MovieStatsHourly   *mongo.Collection = OpenCollection("movie_stats_hourly")
+	MovieStatsDaily    *mongo.Collection = OpenCollection("movie_stats_daily")
+	Trending           *mongo.Collection = OpenCollection("trending")
+	UserActivity       *mongo.Collection = OpenCollection("user_activity")
+	LLMStatsDaily      *mongo.Collection = OpenCollection("llm_stats_daily")
+	SchemaMigrations   *mongo.Collection = OpenCollection("schema_migrations")
+)
@@ -1,11 +1,14 @@
 package database
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"os"
 
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/tracing"
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/v2/event"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
@@ -14,23 +17,28 @@ func DBInstance() *mongo.Client {
 	// Load environment variables from .env file
 	err := godotenv.Load(".env")
 	if err != nil {
-		log.Println("Error loading .env file")
+		logger.Warn("error loading .env file", "error", err)
 	}
-	MongoDb := os.Getenv("MONGO_URI")
-	if MongoDb == "" {
-		log.Fatal("MONGODB_URI not set in environment")
+	// Set client options and connect to MongoDB. The client connects lazily,
+	// so an unset MONGO_URI is left for main to refuse; packages that only
+	// import this one can still load without a database.
+	clientOptions := options.Client().SetMonitor(chainMonitors(metrics.NewMongoMonitor(), tracing.NewMongoMonitor()))
+	if MongoDb := os.Getenv("MONGO_URI"); MongoDb != "" {
+		clientOptions.ApplyURI(MongoDb)
+	} else {
+		logger.Warn("MONGO_URI not set in environment")
 	}
-	// Set client options and connect to MongoDB
-	clientOptions := options.Client().ApplyURI(MongoDb)
 	client, err := mongo.Connect(nil, clientOptions)
-	fmt.Println("MongoDB connected successfully", client)
 	if err != nil {
-		log.Fatal("Error connecting to MongoDB:", err)
-		return nil
+		logger.Error("error connecting to MongoDB", "error", err)
+		os.Exit(1)
 	}
+	logger.Info("MongoDB client created")
 	return client
 }
 
+var logger = logging.For("database")
+
 // Create a global MongoDB client instance
 var Client *mongo.Client = DBInstance()
 
@@ -41,3 +49,25 @@ func OpenCollection(collectionName string) *mongo.Collection {
 	}
 	return collection
 }
+
+// chainMonitors fans each command event out to several monitors, since the
+// driver only accepts one.
+func chainMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				m.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				m.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				m.Failed(ctx, e)
+			}
+		},
+	}
+}
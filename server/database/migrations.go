@@ -0,0 +1,290 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Migration is one versioned schema change. Up must be safe to run again,
+// because two instances booting together may both apply it before either
+// records it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context) error
+}
+
+// AppliedMigration is the record kept in the schema_migrations collection.
+type AppliedMigration struct {
+	Version     int       `bson:"version"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+// migrations must be kept in ascending version order. Never edit or remove a
+// migration once it has shipped; add a new one instead.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create password reset and API key indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			PasswordResets: {
+				{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "user_id", Value: 1}}},
+				// Expired reset tokens are removed by MongoDB a day after they expire.
+				{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(86400)},
+			},
+			APIKeys: {
+				{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "key_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+			},
+		}),
+	},
+	{
+		Version:     2,
+		Description: "create user and movie lookup indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			Users: {
+				{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "identities.provider", Value: 1}, {Key: "identities.subject", Value: 1}}},
+			},
+			Movies: {
+				{Keys: bson.D{{Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				// Recommendations filter on genre and sort by ranking.
+				{Keys: bson.D{{Key: "genre.genre_name", Value: 1}, {Key: "ranking.ranking_value", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     3,
+		Description: "mark users created before email verification as verified",
+		Up: func(ctx context.Context) error {
+			_, err := Users.UpdateMany(ctx,
+				bson.M{"email_verified": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"email_verified": true}},
+			)
+			return err
+		},
+	},
+	{
+		Version:     4,
+		Description: "backfill extended movie details and index release date and runtime",
+		Up: func(ctx context.Context) error {
+			defaults := bson.D{
+				{Key: "release_date", Value: ""},
+				{Key: "runtime_minutes", Value: 0},
+				{Key: "synopsis", Value: ""},
+				{Key: "cast", Value: bson.A{}},
+				{Key: "crew", Value: bson.A{}},
+				{Key: "spoken_languages", Value: bson.A{}},
+				{Key: "country", Value: ""},
+				{Key: "certification", Value: ""},
+			}
+			for _, field := range defaults {
+				_, err := Movies.UpdateMany(ctx,
+					bson.M{field.Key: bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{field.Key: field.Value}},
+				)
+				if err != nil {
+					return fmt.Errorf("backfill %s: %w", field.Key, err)
+				}
+			}
+			return createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+				Movies: {
+					{Keys: bson.D{{Key: "release_date", Value: 1}}},
+					{Keys: bson.D{{Key: "runtime_minutes", Value: 1}}},
+				},
+			})(ctx)
+		},
+	},
+	{
+		Version:     5,
+		Description: "create people from existing movie credits",
+		Up: func(ctx context.Context) error {
+			err := createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+				People: {
+					{Keys: bson.D{{Key: "person_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+					{Keys: bson.D{{Key: "name_key", Value: 1}}, Options: options.Index().SetUnique(true)},
+				},
+				Movies: {
+					{Keys: bson.D{{Key: "cast.person_id", Value: 1}}},
+					{Keys: bson.D{{Key: "crew.person_id", Value: 1}, {Key: "crew.job", Value: 1}}},
+				},
+			})(ctx)
+			if err != nil {
+				return err
+			}
+			movies, err := FindAll[models.Movie](ctx, Movies, bson.M{"$or": bson.A{
+				bson.M{"cast.0": bson.M{"$exists": true}},
+				bson.M{"crew.0": bson.M{"$exists": true}},
+			}})
+			if err != nil {
+				return err
+			}
+			for i := range movies {
+				movie := &movies[i]
+				if err := LinkPeople(ctx, movie); err != nil {
+					return err
+				}
+				_, err := Movies.UpdateOne(ctx, bson.M{"_id": movie.ID}, bson.M{"$set": bson.M{"cast": movie.Cast, "crew": movie.Crew}})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version:     6,
+		Description: "create curated collection indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			CuratedCollections: {
+				{Keys: bson.D{{Key: "collection_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}}},
+				{Keys: bson.D{{Key: "imdb_ids", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     7,
+		Description: "create user list and follow indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			UserLists: {
+				{Keys: bson.D{{Key: "list_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "updated_at", Value: -1}}},
+				// Only unlisted lists carry a share token.
+				{Keys: bson.D{{Key: "share_token", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+				{Keys: bson.D{{Key: "privacy", Value: 1}, {Key: "follower_count", Value: -1}}},
+				{Keys: bson.D{{Key: "imdb_ids", Value: 1}}},
+			},
+			ListFollows: {
+				{Keys: bson.D{{Key: "list_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "followed_at", Value: -1}}},
+			},
+		}),
+	},
+	{
+		Version:     8,
+		Description: "create watch progress indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			WatchProgress: {
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "imdb_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+				// Continue watching lists a user's unfinished movies, latest first.
+				{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "completed", Value: 1}, {Key: "updated_at", Value: -1}}},
+			},
+		}),
+	},
+	{
+		Version:     9,
+		Description: "create movie stats and trending indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			MovieStatsHourly: {
+				{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "start", Value: 1}}, Options: options.Index().SetUnique(true)},
+				// Hourly buckets only feed the trending windows, the longest
+				// of which is a week.
+				{Keys: bson.D{{Key: "start", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(8 * 86400)},
+			},
+			MovieStatsDaily: {
+				{Keys: bson.D{{Key: "imdb_id", Value: 1}, {Key: "start", Value: 1}}, Options: options.Index().SetUnique(true)},
+				{Keys: bson.D{{Key: "start", Value: 1}}},
+			},
+			Trending: {
+				{Keys: bson.D{{Key: "window", Value: 1}, {Key: "computed_at", Value: -1}, {Key: "score", Value: -1}}},
+				{Keys: bson.D{{Key: "window", Value: 1}, {Key: "genre_ids", Value: 1}, {Key: "computed_at", Value: -1}, {Key: "score", Value: -1}}},
+			},
+		}),
+	},
+	{
+		Version:     10,
+		Description: "create analytics indexes",
+		Up: createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+			UserActivity: {
+				{Keys: bson.D{{Key: "day", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+			},
+			LLMStatsDaily: {
+				{Keys: bson.D{{Key: "start", Value: 1}}, Options: options.Index().SetUnique(true)},
+			},
+			Users: {
+				{Keys: bson.D{{Key: "created_at", Value: 1}}},
+			},
+		}),
+	},
+	{
+		Version:     11,
+		Description: "match people on provider ID and allow namesakes",
+		Up: func(ctx context.Context) error {
+			// IndexNotFound means an earlier run already dropped it.
+			var ce mongo.CommandError
+			if err := People.Indexes().DropOne(ctx, "name_key_1"); err != nil && !(errors.As(err, &ce) && ce.HasErrorCode(27)) {
+				return fmt.Errorf("%s: %w", People.Name(), err)
+			}
+			return createIndexes(map[*mongo.Collection][]mongo.IndexModel{
+				People: {
+					{Keys: bson.D{{Key: "name_key", Value: 1}, {Key: "created_at", Value: 1}}},
+					{Keys: bson.D{{Key: "provider_id", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+				},
+			})(ctx)
+		},
+	},
+}
+
+func createIndexes(indexes map[*mongo.Collection][]mongo.IndexModel) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for collection, indexModels := range indexes {
+			if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+				return fmt.Errorf("%s: %w", collection.Name(), err)
+			}
+		}
+		return nil
+	}
+}
+
+// Migrate applies every migration newer than the latest recorded one and
+// returns the versions it applied.
+func Migrate(ctx context.Context) ([]int, error) {
+	if _, err := SchemaMigrations.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return nil, err
+	}
+	applied, err := FindAll[AppliedMigration](ctx, SchemaMigrations, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	done := map[int]bool{}
+	for _, m := range applied {
+		done[m.Version] = true
+	}
+
+	var versions []int
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		logger.Info("applying migration", "version", m.Version, "description", m.Description)
+		if err := m.Up(ctx); err != nil {
+			return versions, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+		}
+		_, err := SchemaMigrations.InsertOne(ctx, AppliedMigration{
+			Version:     m.Version,
+			Description: m.Description,
+			AppliedAt:   time.Now(),
+		})
+		// Another instance recorded the same migration first.
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return versions, err
+		}
+		versions = append(versions, m.Version)
+	}
+	return versions, nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func personNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// LinkPeople points every cast and crew credit on movie at a person, created
+// on first use. Credits with a ProviderID are matched on it alone; the rest
+// fall back to the earliest person with the same name. Credits that already
+// carry a PersonID are left alone.
+func LinkPeople(ctx context.Context, movie *models.Movie) error {
+	ids := map[string]string{}
+	resolve := func(name, providerId string) (string, error) {
+		key := "name:" + personNameKey(name)
+		if providerId != "" {
+			key = "provider:" + providerId
+		}
+		if id, ok := ids[key]; ok {
+			return id, nil
+		}
+		id, err := findOrCreatePerson(ctx, name, providerId)
+		if err != nil {
+			return "", err
+		}
+		ids[key] = id
+		return id, nil
+	}
+	for i := range movie.Cast {
+		if movie.Cast[i].PersonID != "" {
+			continue
+		}
+		id, err := resolve(movie.Cast[i].Name, movie.Cast[i].ProviderID)
+		if err != nil {
+			return err
+		}
+		movie.Cast[i].PersonID = id
+	}
+	for i := range movie.Crew {
+		if movie.Crew[i].PersonID != "" {
+			continue
+		}
+		id, err := resolve(movie.Crew[i].Name, movie.Crew[i].ProviderID)
+		if err != nil {
+			return err
+		}
+		movie.Crew[i].PersonID = id
+	}
+	return nil
+}
+
+func findOrCreatePerson(ctx context.Context, name, providerId string) (string, error) {
+	nameKey := personNameKey(name)
+	personId := bson.NewObjectID().Hex()
+	onInsert := bson.M{
+		"person_id":  personId,
+		"name":       strings.TrimSpace(name),
+		"name_key":   nameKey,
+		"created_at": time.Now(),
+	}
+	if providerId != "" {
+		// Upserting on the unique provider_id keeps concurrent edits from
+		// creating the same person twice.
+		var person models.Person
+		err := People.FindOneAndUpdate(ctx,
+			bson.M{"provider_id": providerId},
+			bson.M{"$setOnInsert": onInsert},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&person)
+		if err != nil {
+			return "", err
+		}
+		return person.PersonID, nil
+	}
+	// Names are not unique, so two edits adding the same new name at once can
+	// each create a person; the earliest is the one matched from then on.
+	person, err := FindOne[models.Person](ctx, People, bson.M{"name_key": nameKey},
+		options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}))
+	if err == nil {
+		return person.PersonID, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return "", err
+	}
+	if _, err := People.InsertOne(ctx, onInsert); err != nil {
+		return "", err
+	}
+	return personId, nil
+}
+
+// CreditedPeople returns the IDs of everyone credited on movie.
+func CreditedPeople(movie *models.Movie) []string {
+	var ids []string
+	for _, credit := range movie.Cast {
+		if credit.PersonID != "" {
+			ids = append(ids, credit.PersonID)
+		}
+	}
+	for _, credit := range movie.Crew {
+		if credit.PersonID != "" {
+			ids = append(ids, credit.PersonID)
+		}
+	}
+	return ids
+}
+
+// PrunePeople deletes the people among ids who are no longer credited on any
+// movie. Call it with the previous credits after a movie is edited or deleted.
+func PrunePeople(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		credited, err := Movies.CountDocuments(ctx, bson.M{"$or": bson.A{
+			bson.M{"cast.person_id": id},
+			bson.M{"crew.person_id": id},
+		}}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if credited > 0 {
+			continue
+		}
+		if _, err := People.DeleteOne(ctx, bson.M{"person_id": id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func FindPersonByID(ctx context.Context, personId string) (*models.Person, error) {
+	return FindOne[models.Person](ctx, People, bson.M{"person_id": personId})
+}
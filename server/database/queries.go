@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// FindAll decodes every document matching filter. It returns an empty slice
+// rather than nil when nothing matches so handlers serialise it as [].
+func FindAll[T any](ctx context.Context, collection *mongo.Collection, filter any, opts ...options.Lister[options.FindOptions]) ([]T, error) {
+	cursor, err := collection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+	results := []T{}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindOne decodes the first document matching filter, returning
+// mongo.ErrNoDocuments when there is none.
+func FindOne[T any](ctx context.Context, collection *mongo.Collection, filter any, opts ...options.Lister[options.FindOneOptions]) (*T, error) {
+	var result T
+	if err := collection.FindOne(ctx, filter, opts...).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func FindUserByID(ctx context.Context, userId string) (*models.User, error) {
+	return FindOne[models.User](ctx, Users, bson.M{"user_id": userId})
+}
+
+func FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return FindOne[models.User](ctx, Users, bson.M{"email": email})
+}
+
+func FindMovieByImdbID(ctx context.Context, imdbId string) (*models.Movie, error) {
+	return FindOne[models.Movie](ctx, Movies, bson.M{"imdb_id": imdbId})
+}
+
+func FindRankings(ctx context.Context) ([]models.Ranking, error) {
+	return FindAll[models.Ranking](ctx, Rankings, bson.M{})
+}
+
+func FindGenres(ctx context.Context) ([]models.Genre, error) {
+	return FindAll[models.Genre](ctx, Genres, bson.M{})
+}
+
+// MovieFilter turns q into a movies filter. Movies with an unknown release
+// date or runtime never match a range on that field, and RankingMax leaves
+// out unranked movies since their sentinel value sorts last.
+func MovieFilter(q models.MovieQuery) bson.M {
+	filter := bson.M{}
+	if len(q.Genres) > 0 {
+		filter["genre.genre_name"] = bson.M{"$in": q.Genres}
+	}
+	if q.YearFrom != nil || q.YearTo != nil {
+		released := bson.M{"$gt": ""}
+		if q.YearFrom != nil {
+			released["$gte"] = fmt.Sprintf("%04d-01-01", *q.YearFrom)
+		}
+		if q.YearTo != nil {
+			released["$lte"] = fmt.Sprintf("%04d-12-31", *q.YearTo)
+		}
+		filter["release_date"] = released
+	}
+	if q.RuntimeMin != nil || q.RuntimeMax != nil {
+		runtime := bson.M{"$gt": 0}
+		if q.RuntimeMin != nil {
+			runtime["$gte"] = *q.RuntimeMin
+		}
+		if q.RuntimeMax != nil {
+			runtime["$lte"] = *q.RuntimeMax
+		}
+		filter["runtime_minutes"] = runtime
+	}
+	if q.RankingMax != nil {
+		filter["ranking.ranking_value"] = bson.M{"$lte": *q.RankingMax}
+	}
+	if q.Language != "" {
+		filter["spoken_languages"] = strings.ToLower(q.Language)
+	}
+	if q.Country != "" {
+		filter["country"] = strings.ToUpper(q.Country)
+	}
+	if q.Certification != "" {
+		filter["certification"] = q.Certification
+	}
+	return filter
+}
@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are never written out.
+var sensitiveKeys = []string{"password", "cookie", "token", "authorization", "secret", "api_key"}
+
+var (
+	root          *slog.Logger
+	defaultLevel  = new(slog.LevelVar)
+	packageLevels = map[string]*slog.LevelVar{}
+)
+
+func init() {
+	// Loggers are created during package initialisation, before main runs,
+	// so the level configuration has to be read here.
+	_ = godotenv.Load(".env")
+	defaultLevel.Set(parseLevel(os.Getenv("LOG_LEVEL"), slog.LevelInfo))
+	// LOG_LEVELS overrides the level per package, e.g. "database=debug,controllers=warn".
+	for _, entry := range strings.Split(os.Getenv("LOG_LEVELS"), ",") {
+		pkg, level, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || pkg == "" {
+			continue
+		}
+		lv := new(slog.LevelVar)
+		lv.Set(parseLevel(level, defaultLevel.Level()))
+		packageLevels[pkg] = lv
+	}
+	root = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		// Filtering happens in levelHandler so that package overrides can lower the level.
+		Level:       slog.LevelDebug,
+		ReplaceAttr: redact,
+	}))
+	slog.SetDefault(root.With("package", "main"))
+}
+
+func parseLevel(s string, fallback slog.Level) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return fallback
+	}
+	return level
+}
+
+func redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return slog.String(a.Key, redacted)
+		}
+	}
+	return a
+}
+
+// levelHandler applies a per-package minimum level on top of the shared handler.
+type levelHandler struct {
+	level slog.Leveler
+	slog.Handler
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{level: h.level, Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{level: h.level, Handler: h.Handler.WithGroup(name)}
+}
+
+// For returns the logger for the named package, honouring any LOG_LEVELS override.
+func For(pkg string) *slog.Logger {
+	var level slog.Leveler = defaultLevel
+	if lv, ok := packageLevels[pkg]; ok {
+		level = lv
+	}
+	return slog.New(&levelHandler{level: level, Handler: root.Handler()}).With("package", pkg)
+}
+
+// FromContext annotates base with the request ID and, once AuthMiddleware has
+// run, the authenticated user's ID and role.
+func FromContext(c *gin.Context, base *slog.Logger) *slog.Logger {
+	var attrs []any
+	for _, key := range []string{"requestId", "userId", "role"} {
+		if v, ok := c.Get(key); ok {
+			attrs = append(attrs, key, v)
+		}
+	}
+	if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+		attrs = append(attrs, "traceId", sc.TraceID().String(), "spanId", sc.SpanID().String())
+	}
+	return base.With(attrs...)
+}
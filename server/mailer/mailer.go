@@ -0,0 +1,112 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+var logger = logging.For("mailer")
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email such as password reset links.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// FromEnv builds the mailer selected by MAILER: "console" (the default),
+// "file" or "smtp".
+func FromEnv() (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@magicstream.local"
+	}
+	switch os.Getenv("MAILER") {
+	case "", "console":
+		return &ConsoleMailer{From: from}, nil
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mail"
+		}
+		return &FileMailer{From: from, Dir: dir}, nil
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			addr = "localhost:1025"
+		}
+		return &SMTPMailer{From: from, Addr: addr, Username: os.Getenv("SMTP_USERNAME"), Password: os.Getenv("SMTP_PASSWORD")}, nil
+	default:
+		return nil, fmt.Errorf("unsupported MAILER %q", os.Getenv("MAILER"))
+	}
+}
+
+// ConsoleMailer logs that a message would have been sent instead of sending
+// it. The body is left out because it holds live reset and verification
+// links; use the file mailer to read messages during development.
+type ConsoleMailer struct {
+	From string
+}
+
+func (m *ConsoleMailer) Send(ctx context.Context, msg Message) error {
+	logger.InfoContext(ctx, "email", "from", m.From, "to", msg.To, "subject", msg.Subject)
+	return nil
+}
+
+// FileMailer writes each message as an .eml file into Dir, which can be opened
+// with any mail client during local development.
+type FileMailer struct {
+	From string
+	Dir  string
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), bson.NewObjectID().Hex())
+	return os.WriteFile(filepath.Join(m.Dir, name), []byte(format(m.From, msg)), 0o644)
+}
+
+// format renders msg as a minimal RFC 5322 message.
+func format(from string, msg Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+	return b.String()
+}
+
+// SMTPMailer sends through an SMTP server, e.g. a local capture server such
+// as MailHog listening on localhost:1025.
+type SMTPMailer struct {
+	From     string
+	Addr     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) Send(_ context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, _ := strings.Cut(m.Addr, ":")
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, []byte(format(m.From, msg)))
+}
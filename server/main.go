@@ -1,32 +1,290 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/backup"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/middleware"
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/routes"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/seed"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/stats"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/tracing"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/utils"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func main() {
-	router := gin.Default()
+	// The database package has loaded .env by now.
+	if os.Getenv("MONGO_URI") == "" {
+		slog.Error("MONGO_URI not set in environment")
+		os.Exit(1)
+	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "keygen":
+			keygen()
+			return
+		case "migrate":
+			migrate()
+			return
+		case "seed":
+			seedDatabase(os.Args[2:])
+			return
+		case "export":
+			exportCollection(os.Args[2:])
+			return
+		case "import":
+			importCollection(os.Args[2:])
+			return
+		}
+	}
+
+	if err := utils.InitKeyring(); err != nil {
+		slog.Error("failed to load JWT signing keys", "error", err)
+		os.Exit(1)
+	}
+	if interval, err := time.ParseDuration(os.Getenv("JWT_ROTATION_INTERVAL")); err == nil && interval > 0 {
+		utils.Keys.StartRotation(context.Background(), interval, utils.RefreshTokenLifetime, func(err error) {
+			slog.Error("failed to rotate JWT signing keys", "error", err)
+		})
+	}
+
+	if os.Getenv("SKIP_MIGRATIONS") != "true" {
+		migrate()
+	} else if utils.RequireEmailVerification() {
+		checkVerificationBackfill()
+	}
+
+	statsInterval := 5 * time.Minute
+	if interval, err := time.ParseDuration(os.Getenv("STATS_AGGREGATE_INTERVAL")); err == nil && interval > 0 {
+		statsInterval = interval
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	aggregatorDone := stats.StartAggregator(ctx, statsInterval, func(err error) {
+		slog.Error("failed to aggregate movie stats", "error", err)
+	})
+
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		slog.Error("failed to initialise tracing", "error", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
+	router := gin.New()
+	router.Use(gin.Recovery())
 
 	config := cors.Config{}
-	config.AllowAllOrigins = true
+	// Credentialed requests need explicit origins; allowing all is only
+	// suitable for local development.
+	if origins := os.Getenv("CORS_ALLOWED_ORIGINS"); origins != "" {
+		config.AllowOrigins = strings.Split(origins, ",")
+	} else {
+		config.AllowAllOrigins = true
+	}
 	config.AllowMethods = []string{"GET", "POST", "PATCH", "PUT", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization"}
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Authorization", middleware.RequestIDHeader, middleware.CSRFHeader, utils.APIKeyHeader, "traceparent", "tracestate", "baggage"}
+	config.ExposeHeaders = []string{"Content-Length", "ETag", middleware.RequestIDHeader}
 	config.AllowCredentials = true
 	config.MaxAge = 12 * time.Hour
 
 	router.Use(cors.New(config))
-	router.Use(gin.Logger())
+	router.Use(otelgin.Middleware(tracing.ServiceName))
+	router.Use(middleware.RequestID())
+	router.Use(middleware.RequestLogger())
+	router.Use(metrics.GinMiddleware())
+
+	metrics.RegisterActiveSessions(func(ctx context.Context) (int64, error) {
+		return database.Users.CountDocuments(ctx, bson.M{"token": bson.M{"$nin": bson.A{"", nil}}})
+	})
 
 	routes.SetUpUnProctectedRoutes(router)
 	routes.SetUpProctectedRoutes(router)
 
-	if err := router.Run(":8080"); err != nil {
-		fmt.Println("Failed to start server:", err)
+	server := &http.Server{Addr: ":8080", Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("failed to start server", "error", err)
+			stop()
+		}
+	}()
+	// Metrics get their own listener so that it can be bound to an address
+	// the public cannot reach.
+	var metricsServer *http.Server
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		metricsServer = &http.Server{Addr: addr, Handler: metrics.InternalHandler()}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("failed to start metrics server", "error", err)
+				stop()
+			}
+		}()
+	}
+	<-ctx.Done()
+	stop()
+	slog.Info("shutting down")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to shut down server", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shut down metrics server", "error", err)
+		}
+	}
+	// Counts recorded since the last aggregation would otherwise be lost.
+	<-aggregatorDone
+	if err := stats.Flush(shutdownCtx); err != nil {
+		slog.Error("failed to flush stats", "error", err)
+	}
+}
+
+// keygen adds a new signing key to JWT_KEY_DIR, making it the active key.
+func keygen() {
+	ring, err := utils.LoadKeyring()
+	if err != nil {
+		slog.Error("failed to load JWT signing keys", "error", err)
+		os.Exit(1)
+	}
+	kid, err := ring.Generate()
+	if err != nil {
+		slog.Error("failed to generate JWT signing key", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("generated JWT signing key", "kid", kid)
+}
+
+// migrate applies pending schema migrations and exits on failure.
+func migrate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	applied, err := database.Migrate(ctx)
+	if err != nil {
+		slog.Error("failed to apply database migrations", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("database schema is up to date", "applied", applied)
+}
+
+// checkVerificationBackfill refuses to start with email verification required
+// while accounts from before it existed have no email_verified flag, which
+// would lock every one of them out. Applying the migrations backfills it.
+func checkVerificationBackfill() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	n, err := database.Users.CountDocuments(ctx, bson.M{"email_verified": bson.M{"$exists": false}}, options.Count().SetLimit(1))
+	if err != nil {
+		slog.Error("failed to check email verification backfill", "error", err)
+		os.Exit(1)
+	}
+	if n > 0 {
+		slog.Error("REQUIRE_EMAIL_VERIFICATION is set but existing users have not been backfilled; run the migrations first")
+		os.Exit(1)
+	}
+}
+
+// seedDatabase loads the development fixtures, applying migrations first so
+// the natural key indexes exist.
+func seedDatabase(args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	dir := flags.String("dir", "data/fixtures", "directory holding the fixture files")
+	reset := flags.Bool("reset", false, "empty the seeded collections before loading")
+	_ = flags.Parse(args)
+	if *reset && gin.Mode() == gin.ReleaseMode {
+		slog.Error("refusing to reset the database in release mode")
+		os.Exit(1)
+	}
+
+	migrate()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	results, err := seed.Load(ctx, *dir, *reset)
+	if err != nil {
+		slog.Error("failed to seed database", "error", err)
+		os.Exit(1)
+	}
+	for _, r := range results {
+		slog.Info("seeded collection", "collection", r.Collection, "inserted", r.Inserted, "updated", r.Updated)
+	}
+}
+
+// exportCollection writes one collection to a file. Logs go to stdout, so the
+// export is never written there.
+func exportCollection(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	name := flags.String("collection", "movies", "collection to export: movies, genres, rankings or users")
+	format := flags.String("format", backup.FormatJSONL, "output format: jsonl or csv")
+	out := flags.String("out", "", "output file (default <collection>.<format>)")
+	_ = flags.Parse(args)
+	if *out == "" {
+		*out = *name + "." + *format
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		slog.Error("failed to create export file", "error", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	err = backup.Export(ctx, file, *name, *format)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		slog.Error("failed to export collection", "collection", *name, "error", err)
+		os.Exit(1)
+	}
+	slog.Info("exported collection", "collection", *name, "file", *out)
+}
+
+// importCollection loads a file produced by export and logs the import report.
+func importCollection(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	name := flags.String("collection", "movies", "collection to import: movies, genres or rankings")
+	format := flags.String("format", backup.FormatJSONL, "input format: jsonl or csv")
+	in := flags.String("in", "", "input file")
+	overwrite := flags.Bool("overwrite", false, "replace records whose natural key already exists")
+	dryRun := flags.Bool("dry-run", false, "validate and report without writing")
+	_ = flags.Parse(args)
+	if *in == "" {
+		slog.Error("import needs an input file, pass -in")
+		os.Exit(2)
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		slog.Error("failed to open import file", "error", err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+	report, err := backup.Import(ctx, file, *name, *format, backup.ImportOptions{Overwrite: *overwrite, DryRun: *dryRun})
+	if report != nil {
+		slog.Info("import finished", "collection", report.Collection, "inserted", report.Inserted, "replaced", report.Replaced,
+			"conflicts", report.Conflicts, "invalid", report.Invalid, "dryRun", report.DryRun)
+	}
+	if err != nil {
+		slog.Error("failed to import collection", "collection", *name, "error", err)
+		os.Exit(1)
 	}
 }
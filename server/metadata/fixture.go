@@ -0,0 +1,37 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FixtureProvider serves metadata from <Dir>/<imdb_id>.json files, for local
+// development and tests without network access.
+type FixtureProvider struct {
+	Dir string
+}
+
+func (p *FixtureProvider) Lookup(_ context.Context, imdbId string) (*Metadata, error) {
+	// The ID becomes part of a path, so anything else is rejected outright.
+	if !ValidImdbID(imdbId) {
+		return nil, ErrNotFound
+	}
+	path := filepath.Join(p.Dir, imdbId+".json")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var md Metadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	md.ImdbID = imdbId
+	return &md, nil
+}
@@ -0,0 +1,183 @@
+// Package metadata looks up movie details by IMDb ID from an external
+// catalogue so admins do not have to type them in by hand.
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+)
+
+var (
+	ErrNotConfigured = errors.New("metadata provider is not configured")
+	ErrNotFound      = errors.New("movie not found at metadata provider")
+)
+
+// imdbIDPattern matches IMDb title IDs such as tt0111161.
+var imdbIDPattern = regexp.MustCompile(`^tt\d{7,10}$`)
+
+func ValidImdbID(id string) bool {
+	return imdbIDPattern.MatchString(id)
+}
+
+// Credit is one cast or crew member. Cast members have a Character, crew
+// members a Job.
+type Credit struct {
+	ProviderID string `json:"provider_id,omitempty"`
+	Name       string `json:"name"`
+	Character  string `json:"character,omitempty"`
+	Job        string `json:"job,omitempty"`
+}
+
+// Metadata is what a provider knows about one movie.
+type Metadata struct {
+	ImdbID           string   `json:"imdb_id"`
+	Title            string   `json:"title"`
+	Year             int      `json:"year,omitempty"`
+	ReleaseDate      string   `json:"release_date,omitempty"`
+	RuntimeMinutes   int      `json:"runtime_minutes,omitempty"`
+	Synopsis         string   `json:"synopsis,omitempty"`
+	PosterURL        string   `json:"poster_url,omitempty"`
+	TrailerYouTubeID string   `json:"trailer_youtube_id,omitempty"`
+	Genres           []string `json:"genres"`
+	Cast             []Credit `json:"cast"`
+	Crew             []Credit `json:"crew"`
+	SpokenLanguages  []string `json:"spoken_languages"`
+	Country          string   `json:"country,omitempty"`
+	Certification    string   `json:"certification,omitempty"`
+}
+
+// Provider fetches metadata for an IMDb ID, returning ErrNotFound when the
+// provider does not know the movie.
+type Provider interface {
+	Lookup(ctx context.Context, imdbId string) (*Metadata, error)
+}
+
+// FromEnv builds the provider selected by METADATA_PROVIDER: "tmdb" for a
+// TMDB-compatible HTTP API, "fixture" for JSON files in METADATA_FIXTURE_DIR,
+// or "none" (the default), which reports ErrNotConfigured on every lookup.
+func FromEnv() (Provider, error) {
+	switch os.Getenv("METADATA_PROVIDER") {
+	case "", "none":
+		return disabled{}, nil
+	case "tmdb":
+		p, err := NewTMDBProvider(os.Getenv("METADATA_BASE_URL"), os.Getenv("METADATA_API_KEY"), os.Getenv("METADATA_API_TOKEN"))
+		if err != nil {
+			return nil, err
+		}
+		if region := os.Getenv("METADATA_REGION"); region != "" {
+			p.Region = strings.ToUpper(region)
+		}
+		return p, nil
+	case "fixture":
+		dir := os.Getenv("METADATA_FIXTURE_DIR")
+		if dir == "" {
+			dir = "data/metadata"
+		}
+		return &FixtureProvider{Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unsupported METADATA_PROVIDER %q", os.Getenv("METADATA_PROVIDER"))
+	}
+}
+
+type disabled struct{}
+
+func (disabled) Lookup(context.Context, string) (*Metadata, error) {
+	return nil, ErrNotConfigured
+}
+
+// genreAliases maps provider genre names to ours where they differ.
+var genreAliases = map[string]string{
+	"science fiction":    "sci-fi",
+	"sci-fi & fantasy":   "sci-fi",
+	"action & adventure": "action",
+}
+
+// MapGenres matches provider genre names to the genres collection, ignoring
+// case. Names with no match are returned separately so admins can add them.
+func MapGenres(names []string, genres []models.Genre) ([]models.Genre, []string) {
+	byName := make(map[string]models.Genre, len(genres))
+	for _, g := range genres {
+		byName[strings.ToLower(g.GenreName)] = g
+	}
+	mapped := []models.Genre{}
+	unmapped := []string{}
+	seen := map[int]bool{}
+	for _, name := range names {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if alias, ok := genreAliases[key]; ok {
+			key = alias
+		}
+		g, ok := byName[key]
+		if !ok {
+			unmapped = append(unmapped, name)
+			continue
+		}
+		if !seen[g.GenreID] {
+			seen[g.GenreID] = true
+			mapped = append(mapped, g)
+		}
+	}
+	return mapped, unmapped
+}
+
+// Apply copies metadata into movie. Without overwrite only empty fields are
+// filled, so details an admin typed in are kept. It returns the provider
+// genres that have no match in genres.
+func Apply(movie *models.Movie, md *Metadata, genres []models.Genre, overwrite bool) []string {
+	if movie.ImdbID == "" {
+		movie.ImdbID = md.ImdbID
+	}
+	if md.Title != "" && (overwrite || movie.Title == "") {
+		movie.Title = md.Title
+	}
+	if md.PosterURL != "" && (overwrite || movie.PosterPath == "") {
+		movie.PosterPath = md.PosterURL
+	}
+	if md.TrailerYouTubeID != "" && (overwrite || movie.YouTubeID == "") {
+		movie.YouTubeID = md.TrailerYouTubeID
+	}
+	if md.ReleaseDate != "" && (overwrite || movie.ReleaseDate == "") {
+		movie.ReleaseDate = md.ReleaseDate
+	}
+	if md.RuntimeMinutes > 0 && (overwrite || movie.RuntimeMinutes == 0) {
+		movie.RuntimeMinutes = md.RuntimeMinutes
+	}
+	if md.Synopsis != "" && (overwrite || movie.Synopsis == "") {
+		movie.Synopsis = md.Synopsis
+	}
+	if len(md.Cast) > 0 && (overwrite || len(movie.Cast) == 0) {
+		movie.Cast = make([]models.CastMember, 0, len(md.Cast))
+		for _, credit := range md.Cast {
+			movie.Cast = append(movie.Cast, models.CastMember{ProviderID: credit.ProviderID, Name: credit.Name, Character: credit.Character})
+		}
+	}
+	if len(md.Crew) > 0 && (overwrite || len(movie.Crew) == 0) {
+		movie.Crew = make([]models.CrewMember, 0, len(md.Crew))
+		for _, credit := range md.Crew {
+			movie.Crew = append(movie.Crew, models.CrewMember{ProviderID: credit.ProviderID, Name: credit.Name, Job: credit.Job})
+		}
+	}
+	if len(md.SpokenLanguages) > 0 && (overwrite || len(movie.SpokenLanguages) == 0) {
+		movie.SpokenLanguages = md.SpokenLanguages
+	}
+	if md.Country != "" && (overwrite || movie.Country == "") {
+		movie.Country = md.Country
+	}
+	if md.Certification != "" && (overwrite || movie.Certification == "") {
+		movie.Certification = md.Certification
+	}
+	mapped, unmapped := MapGenres(md.Genres, genres)
+	if len(mapped) > 0 && (overwrite || len(movie.Genre) == 0) {
+		movie.Genre = mapped
+	}
+	if movie.Ranking.RankingValue == 0 {
+		movie.Ranking = models.NotRanked
+	}
+	return unmapped
+}
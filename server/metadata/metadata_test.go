@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+)
+
+var testGenres = []models.Genre{
+	{GenreID: 1, GenreName: "Action"},
+	{GenreID: 2, GenreName: "Sci-Fi"},
+	{GenreID: 3, GenreName: "Drama"},
+}
+
+func TestMapGenres(t *testing.T) {
+	mapped, unmapped := MapGenres([]string{"drama", "Science Fiction", "Action & Adventure", "Action", "Documentary"}, testGenres)
+	wantMapped := []models.Genre{testGenres[2], testGenres[1], testGenres[0]}
+	if !reflect.DeepEqual(mapped, wantMapped) {
+		t.Errorf("mapped = %v, want %v", mapped, wantMapped)
+	}
+	if !reflect.DeepEqual(unmapped, []string{"Documentary"}) {
+		t.Errorf("unmapped = %v, want [Documentary]", unmapped)
+	}
+}
+
+func TestMapGenresEmpty(t *testing.T) {
+	mapped, unmapped := MapGenres(nil, testGenres)
+	if mapped == nil || len(mapped) != 0 || unmapped == nil || len(unmapped) != 0 {
+		t.Errorf("got %v, %v, want two empty non-nil slices", mapped, unmapped)
+	}
+}
+
+func lookupFixture(t *testing.T, imdbId string) *Metadata {
+	t.Helper()
+	provider := &FixtureProvider{Dir: "../data/metadata"}
+	md, err := provider.Lookup(context.Background(), imdbId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return md
+}
+
+func TestFixtureProvider(t *testing.T) {
+	md := lookupFixture(t, "tt1375666")
+	if md.ImdbID != "tt1375666" || md.Title != "Inception" || md.RuntimeMinutes != 148 {
+		t.Errorf("unexpected metadata: %+v", md)
+	}
+	provider := &FixtureProvider{Dir: "../data/metadata"}
+	for _, id := range []string{"tt0000001", "../fixtures/users", ""} {
+		if _, err := provider.Lookup(context.Background(), id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Lookup(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+}
+
+func TestApplyFillsEmptyFields(t *testing.T) {
+	md := lookupFixture(t, "tt1375666")
+	movie := models.Movie{
+		Title:   "Inception (Director's Cut)",
+		Cast:    []models.CastMember{{Name: "Someone Else"}},
+		Country: "GB",
+	}
+	unmapped := Apply(&movie, md, testGenres, false)
+
+	if movie.Title != "Inception (Director's Cut)" || movie.Country != "GB" {
+		t.Errorf("typed in fields were overwritten: title %q, country %q", movie.Title, movie.Country)
+	}
+	if len(movie.Cast) != 1 || movie.Cast[0].Name != "Someone Else" {
+		t.Errorf("typed in cast was overwritten: %v", movie.Cast)
+	}
+	if movie.ImdbID != "tt1375666" || movie.RuntimeMinutes != 148 || movie.YouTubeID != "YoHD9XEInc0" || movie.Certification != "PG-13" {
+		t.Errorf("empty fields were not filled: %+v", movie)
+	}
+	if len(movie.Crew) != 3 || movie.Crew[0].Name != "Christopher Nolan" || movie.Crew[0].Job != "Director" || movie.Crew[0].ProviderID != "525" {
+		t.Errorf("crew = %v", movie.Crew)
+	}
+	if !reflect.DeepEqual(movie.Genre, []models.Genre{testGenres[0], testGenres[1]}) {
+		t.Errorf("genres = %v", movie.Genre)
+	}
+	if !reflect.DeepEqual(unmapped, []string{"Adventure"}) {
+		t.Errorf("unmapped = %v, want [Adventure]", unmapped)
+	}
+	if movie.Ranking != models.NotRanked {
+		t.Errorf("ranking = %v, want NotRanked", movie.Ranking)
+	}
+}
+
+func TestApplyOverwrite(t *testing.T) {
+	md := lookupFixture(t, "tt1375666")
+	movie := models.Movie{
+		ImdbID:  "tt1375666",
+		Title:   "Old Title",
+		Cast:    []models.CastMember{{Name: "Someone Else"}},
+		Genre:   []models.Genre{testGenres[2]},
+		Country: "GB",
+		Ranking: models.Ranking{RankingValue: 1, RankingName: "Excellent"},
+	}
+	Apply(&movie, md, testGenres, true)
+
+	if movie.Title != "Inception" || movie.Country != "US" {
+		t.Errorf("fields were not overwritten: title %q, country %q", movie.Title, movie.Country)
+	}
+	if len(movie.Cast) != 4 || movie.Cast[0].Name != "Leonardo DiCaprio" || movie.Cast[0].Character != "Dom Cobb" || movie.Cast[0].ProviderID != "6193" {
+		t.Errorf("cast = %v", movie.Cast)
+	}
+	if !reflect.DeepEqual(movie.Genre, []models.Genre{testGenres[0], testGenres[1]}) {
+		t.Errorf("genres = %v", movie.Genre)
+	}
+	if movie.Ranking.RankingValue != 1 {
+		t.Errorf("an existing ranking was replaced: %v", movie.Ranking)
+	}
+}
+
+func TestApplyKeepsFieldsTheProviderLacks(t *testing.T) {
+	movie := models.Movie{Title: "Kept", RuntimeMinutes: 90, Genre: []models.Genre{testGenres[2]}}
+	Apply(&movie, &Metadata{ImdbID: "tt0000001", Genres: []string{"Unknown"}}, testGenres, true)
+	if movie.Title != "Kept" || movie.RuntimeMinutes != 90 || len(movie.Genre) != 1 {
+		t.Errorf("empty metadata cleared fields: %+v", movie)
+	}
+}
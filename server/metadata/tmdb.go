@@ -0,0 +1,213 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTMDBBaseURL  = "https://api.themoviedb.org/3"
+	defaultTMDBImageURL = "https://image.tmdb.org/t/p/w500"
+	// maxCast keeps the top billed cast members only.
+	maxCast = 15
+)
+
+// crewJobs are the crew credits worth keeping; the rest are dropped.
+var crewJobs = map[string]bool{
+	"Director":                true,
+	"Screenplay":              true,
+	"Writer":                  true,
+	"Producer":                true,
+	"Original Music Composer": true,
+}
+
+// TMDBProvider talks to the TMDB v3 API or any service exposing the same
+// find and movie endpoints.
+type TMDBProvider struct {
+	BaseURL  string
+	ImageURL string
+	// APIKey is sent as the api_key query parameter; Token, a v4 read access
+	// token, is sent as a bearer token. One of them is required.
+	APIKey string
+	Token  string
+	// Region selects whose age certification is used, e.g. "US".
+	Region string
+	Client *http.Client
+}
+
+func NewTMDBProvider(baseURL, apiKey, token string) (*TMDBProvider, error) {
+	if apiKey == "" && token == "" {
+		return nil, errors.New("METADATA_API_KEY or METADATA_API_TOKEN must be set for the tmdb provider")
+	}
+	if baseURL == "" {
+		baseURL = defaultTMDBBaseURL
+	}
+	return &TMDBProvider{
+		BaseURL:  strings.TrimRight(baseURL, "/"),
+		ImageURL: defaultTMDBImageURL,
+		APIKey:   apiKey,
+		Token:    token,
+		Region:   "US",
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+type tmdbFindResponse struct {
+	MovieResults []struct {
+		ID int `json:"id"`
+	} `json:"movie_results"`
+}
+
+type tmdbMovie struct {
+	Title       string `json:"title"`
+	ReleaseDate string `json:"release_date"`
+	Runtime     int    `json:"runtime"`
+	Overview    string `json:"overview"`
+	PosterPath  string `json:"poster_path"`
+	Genres      []struct {
+		Name string `json:"name"`
+	} `json:"genres"`
+	Credits struct {
+		Cast []struct {
+			ID        int    `json:"id"`
+			Name      string `json:"name"`
+			Character string `json:"character"`
+		} `json:"cast"`
+		Crew []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+			Job  string `json:"job"`
+		} `json:"crew"`
+	} `json:"credits"`
+	SpokenLanguages []struct {
+		Code string `json:"iso_639_1"`
+	} `json:"spoken_languages"`
+	ProductionCountries []struct {
+		Code string `json:"iso_3166_1"`
+	} `json:"production_countries"`
+	ReleaseDates struct {
+		Results []struct {
+			Country      string `json:"iso_3166_1"`
+			ReleaseDates []struct {
+				Certification string `json:"certification"`
+			} `json:"release_dates"`
+		} `json:"results"`
+	} `json:"release_dates"`
+	Videos struct {
+		Results []struct {
+			Key      string `json:"key"`
+			Site     string `json:"site"`
+			Type     string `json:"type"`
+			Official bool   `json:"official"`
+		} `json:"results"`
+	} `json:"videos"`
+}
+
+func (p *TMDBProvider) Lookup(ctx context.Context, imdbId string) (*Metadata, error) {
+	if !ValidImdbID(imdbId) {
+		return nil, ErrNotFound
+	}
+	var found tmdbFindResponse
+	if err := p.get(ctx, "/find/"+imdbId, url.Values{"external_source": {"imdb_id"}}, &found); err != nil {
+		return nil, err
+	}
+	if len(found.MovieResults) == 0 {
+		return nil, ErrNotFound
+	}
+	var movie tmdbMovie
+	path := "/movie/" + strconv.Itoa(found.MovieResults[0].ID)
+	if err := p.get(ctx, path, url.Values{"append_to_response": {"credits,videos,release_dates"}}, &movie); err != nil {
+		return nil, err
+	}
+
+	md := &Metadata{
+		ImdbID:          imdbId,
+		Title:           movie.Title,
+		ReleaseDate:     movie.ReleaseDate,
+		RuntimeMinutes:  movie.Runtime,
+		Synopsis:        movie.Overview,
+		Genres:          []string{},
+		Cast:            []Credit{},
+		Crew:            []Credit{},
+		SpokenLanguages: []string{},
+	}
+	if len(movie.ReleaseDate) >= 4 {
+		md.Year, _ = strconv.Atoi(movie.ReleaseDate[:4])
+	}
+	if movie.PosterPath != "" {
+		md.PosterURL = p.ImageURL + movie.PosterPath
+	}
+	for _, g := range movie.Genres {
+		md.Genres = append(md.Genres, g.Name)
+	}
+	for i, c := range movie.Credits.Cast {
+		if i == maxCast {
+			break
+		}
+		md.Cast = append(md.Cast, Credit{ProviderID: strconv.Itoa(c.ID), Name: c.Name, Character: c.Character})
+	}
+	for _, c := range movie.Credits.Crew {
+		if crewJobs[c.Job] {
+			md.Crew = append(md.Crew, Credit{ProviderID: strconv.Itoa(c.ID), Name: c.Name, Job: c.Job})
+		}
+	}
+	for _, l := range movie.SpokenLanguages {
+		md.SpokenLanguages = append(md.SpokenLanguages, l.Code)
+	}
+	// The first production country is taken as the country of origin.
+	if len(movie.ProductionCountries) > 0 {
+		md.Country = movie.ProductionCountries[0].Code
+	}
+	for _, r := range movie.ReleaseDates.Results {
+		if r.Country != p.Region {
+			continue
+		}
+		for _, d := range r.ReleaseDates {
+			if md.Certification == "" && d.Certification != "" {
+				md.Certification = d.Certification
+			}
+		}
+	}
+	// Prefer an official YouTube trailer, then any YouTube trailer.
+	for _, official := range []bool{true, false} {
+		for _, v := range movie.Videos.Results {
+			if md.TrailerYouTubeID == "" && v.Site == "YouTube" && v.Type == "Trailer" && (v.Official || !official) {
+				md.TrailerYouTubeID = v.Key
+			}
+		}
+	}
+	return md, nil
+}
+
+func (p *TMDBProvider) get(ctx context.Context, path string, query url.Values, out any) error {
+	if p.APIKey != "" {
+		query.Set("api_key", p.APIKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("metadata provider returned %s for %s", resp.Status, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
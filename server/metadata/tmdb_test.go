@@ -0,0 +1,149 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeTMDB serves the two endpoints TMDBProvider uses for one movie.
+func fakeTMDB(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/find/tt1375666", func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		if r.URL.Query().Get("external_source") != "imdb_id" {
+			t.Errorf("external_source = %q", r.URL.Query().Get("external_source"))
+		}
+		fmt.Fprint(w, `{"movie_results": [{"id": 27205}]}`)
+	})
+	mux.HandleFunc("/find/tt0000001", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"movie_results": []}`)
+	})
+	mux.HandleFunc("/find/tt0000002", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/movie/27205", func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		if got := r.URL.Query().Get("append_to_response"); got != "credits,videos,release_dates" {
+			t.Errorf("append_to_response = %q", got)
+		}
+		cast := []map[string]any{}
+		for i := range maxCast + 5 {
+			cast = append(cast, map[string]any{"id": i, "name": fmt.Sprintf("Actor %d", i), "character": "Role"})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"title":        "Inception",
+			"release_date": "2010-07-15",
+			"runtime":      148,
+			"overview":     "A thief who steals secrets.",
+			"poster_path":  "/poster.jpg",
+			"genres":       []map[string]string{{"name": "Action"}, {"name": "Science Fiction"}},
+			"credits": map[string]any{
+				"cast": cast,
+				"crew": []map[string]any{
+					{"id": 525, "name": "Christopher Nolan", "job": "Director"},
+					{"id": 1, "name": "Someone", "job": "Gaffer"},
+					{"id": 947, "name": "Hans Zimmer", "job": "Original Music Composer"},
+				},
+			},
+			"spoken_languages":     []map[string]string{{"iso_639_1": "en"}, {"iso_639_1": "ja"}},
+			"production_countries": []map[string]string{{"iso_3166_1": "US"}, {"iso_3166_1": "GB"}},
+			"release_dates": map[string]any{"results": []map[string]any{
+				{"iso_3166_1": "GB", "release_dates": []map[string]string{{"certification": "12A"}}},
+				{"iso_3166_1": "US", "release_dates": []map[string]string{{"certification": ""}, {"certification": "PG-13"}}},
+			}},
+			"videos": map[string]any{"results": []map[string]any{
+				{"key": "teaser", "site": "YouTube", "type": "Teaser", "official": true},
+				{"key": "fan", "site": "YouTube", "type": "Trailer", "official": false},
+				{"key": "vimeo", "site": "Vimeo", "type": "Trailer", "official": true},
+				{"key": "official", "site": "YouTube", "type": "Trailer", "official": true},
+			}},
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTMDBLookup(t *testing.T) {
+	server := fakeTMDB(t, func(r *http.Request) {
+		if r.URL.Query().Get("api_key") != "secret" {
+			t.Errorf("api_key = %q", r.URL.Query().Get("api_key"))
+		}
+	})
+	provider, err := NewTMDBProvider(server.URL+"/", "secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := provider.Lookup(context.Background(), "tt1375666")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Title != "Inception" || md.Year != 2010 || md.RuntimeMinutes != 148 || md.PosterURL != defaultTMDBImageURL+"/poster.jpg" {
+		t.Errorf("unexpected details: %+v", md)
+	}
+	if len(md.Cast) != maxCast {
+		t.Errorf("got %d cast members, want %d", len(md.Cast), maxCast)
+	}
+	wantCrew := []Credit{
+		{ProviderID: "525", Name: "Christopher Nolan", Job: "Director"},
+		{ProviderID: "947", Name: "Hans Zimmer", Job: "Original Music Composer"},
+	}
+	if !reflect.DeepEqual(md.Crew, wantCrew) {
+		t.Errorf("crew = %v, want %v", md.Crew, wantCrew)
+	}
+	if !reflect.DeepEqual(md.Genres, []string{"Action", "Science Fiction"}) || !reflect.DeepEqual(md.SpokenLanguages, []string{"en", "ja"}) {
+		t.Errorf("genres %v, languages %v", md.Genres, md.SpokenLanguages)
+	}
+	if md.Country != "US" || md.Certification != "PG-13" || md.TrailerYouTubeID != "official" {
+		t.Errorf("country %q, certification %q, trailer %q", md.Country, md.Certification, md.TrailerYouTubeID)
+	}
+}
+
+func TestTMDBLookupWithTokenAndRegion(t *testing.T) {
+	server := fakeTMDB(t, func(r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" || r.URL.Query().Has("api_key") {
+			t.Errorf("Authorization = %q, api_key = %q", r.Header.Get("Authorization"), r.URL.Query().Get("api_key"))
+		}
+	})
+	provider, err := NewTMDBProvider(server.URL, "", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider.Region = "GB"
+	md, err := provider.Lookup(context.Background(), "tt1375666")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Certification != "12A" {
+		t.Errorf("certification = %q, want 12A", md.Certification)
+	}
+}
+
+func TestTMDBLookupErrors(t *testing.T) {
+	server := fakeTMDB(t, func(*http.Request) {})
+	provider, err := NewTMDBProvider(server.URL, "secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"tt0000001", "tt0000003", "not-an-id"} {
+		if _, err := provider.Lookup(context.Background(), id); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Lookup(%q) error = %v, want ErrNotFound", id, err)
+		}
+	}
+	if _, err := provider.Lookup(context.Background(), "tt0000002"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup of a failing provider: error = %v", err)
+	}
+}
+
+func TestNewTMDBProviderNeedsCredentials(t *testing.T) {
+	if _, err := NewTMDBProvider("", "", ""); err == nil {
+		t.Error("expected an error without an API key or token")
+	}
+}
@@ -0,0 +1,174 @@
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+var (
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_http_requests_total",
+		Help: "Number of HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "magicstream_http_request_duration_seconds",
+		Help:    "HTTP request latency, by method, route and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	MongoDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "magicstream_mongo_operation_duration_seconds",
+		Help:    "MongoDB command latency, by collection, command and outcome.",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "command", "outcome"})
+
+	LLMRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_llm_ranking_requests_total",
+		Help: "Number of LLM review ranking calls, by outcome.",
+	}, []string{"outcome"})
+
+	LLMDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "magicstream_llm_ranking_duration_seconds",
+		Help:    "LLM review ranking call latency.",
+		Buckets: []float64{.1, .25, .5, 1, 2, 5, 10, 20, 30, 60},
+	})
+
+	LoginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_login_failures_total",
+		Help: "Number of failed login attempts, by reason.",
+	}, []string{"reason"})
+
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_rate_limited_requests_total",
+		Help: "Number of requests rejected by a rate limiter, by limiter.",
+	}, []string{"limiter"})
+
+	FeedRails = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_feed_rails_total",
+		Help: "Number of home feed rails built, by rail kind and outcome.",
+	}, []string{"kind", "outcome"})
+
+	FeedCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_feed_cache_requests_total",
+		Help: "Number of home feed requests, by whether they were served from cache.",
+	}, []string{"result"})
+
+	ResponseCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "magicstream_response_cache_requests_total",
+		Help: "Number of cacheable GET requests, by cache prefix and whether they were served from cache.",
+	}, []string{"prefix", "result"})
+)
+
+// Handler serves the registered collectors in the Prometheus text format
+// to scrapers that send token as a bearer token. The metrics name routes
+// and count failed logins, so they are not for anyone who asks.
+func Handler(token string) gin.HandlerFunc {
+	exporter := promhttp.Handler()
+	return func(c *gin.Context) {
+		scheme, presented, _ := strings.Cut(c.GetHeader("Authorization"), " ")
+		if token == "" || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			c.Abort()
+			return
+		}
+		exporter.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// InternalHandler serves the collectors without authentication, for a
+// listener that only the scraper can reach.
+func InternalHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// GinMiddleware records the request count and latency for every route.
+// Unmatched paths are grouped under a single label to keep cardinality bounded.
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// ObserveLLMCall records the outcome and latency of a single ranking call.
+func ObserveLLMCall(start time.Time, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	LLMRequests.WithLabelValues(outcome).Inc()
+	LLMDuration.Observe(time.Since(start).Seconds())
+}
+
+// RegisterActiveSessions exposes the number of users currently holding a
+// session. The count is evaluated lazily on every scrape.
+func RegisterActiveSessions(count func(ctx context.Context) (int64, error)) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "magicstream_active_sessions",
+		Help: "Number of users with an active session.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		n, err := count(ctx)
+		if err != nil {
+			return 0
+		}
+		return float64(n)
+	})
+}
+
+// mongoCommand keeps what we need from a started event until it finishes.
+type mongoCommand struct {
+	collection string
+	command    string
+}
+
+// NewMongoMonitor returns a command monitor that feeds MongoDuration.
+func NewMongoMonitor() *event.CommandMonitor {
+	var inflight sync.Map
+	finish := func(requestID int64, duration time.Duration, outcome string) {
+		v, ok := inflight.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		cmd := v.(mongoCommand)
+		MongoDuration.WithLabelValues(cmd.collection, cmd.command, outcome).Observe(duration.Seconds())
+	}
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			collection := "none"
+			// The collection name is the value of the first element for CRUD commands, e.g. {"find": "movies"}.
+			if elem, err := e.Command.IndexErr(0); err == nil {
+				if name, ok := elem.Value().StringValueOK(); ok {
+					collection = name
+				}
+			}
+			inflight.Store(e.RequestID, mongoCommand{collection: collection, command: e.CommandName})
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, e.Duration, "success")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, e.Duration, "failure")
+		},
+	}
+}
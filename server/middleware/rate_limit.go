@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// Limit allows Requests per Period, refilled continuously, with bursts of up to Requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// LimitFromEnv parses a limit such as "10/1m" from the named variable,
+// falling back to def when it is unset or malformed.
+func LimitFromEnv(name string, def Limit) Limit {
+	value := os.Getenv(name)
+	count, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return def
+	}
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests <= 0 {
+		return def
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return def
+	}
+	return Limit{Requests: requests, Period: duration}
+}
+
+// RateLimitStore holds the token buckets. The in-memory store is used by
+// default; a shared store lets several server instances enforce one limit.
+type RateLimitStore interface {
+	// Take removes a token from the bucket for key. When the bucket is empty it
+	// reports false and how long until the next token is available.
+	Take(key string, limit Limit) (bool, time.Duration)
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+	period   time.Duration
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit Limit) (bool, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	perToken := limit.Period / time.Duration(limit.Requests)
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), lastSeen: now, period: limit.Period}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Requests), b.tokens+float64(now.Sub(b.lastSeen))/float64(perToken))
+	b.lastSeen = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) * float64(perToken))
+}
+
+// sweep drops buckets that have been idle long enough to be full again.
+func (s *MemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.lastSeen) > b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// KeyFunc extracts the value a limit is applied to. An empty key skips the limit.
+type KeyFunc func(c *gin.Context) string
+
+func ByIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByEmail keys on the "email" field of a JSON body. The body is restored so
+// the handler can still bind it.
+func ByEmail(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
+
+// RateLimit rejects requests with 429 and a Retry-After header once the
+// bucket selected by key is exhausted.
+func RateLimit(store RateLimitStore, name string, limit Limit, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" {
+			c.Next()
+			return
+		}
+		allowed, retryAfter := store.Take(name+":"+k, limit)
+		if !allowed {
+			metrics.RateLimited.WithLabelValues(name).Inc()
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please try again later"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
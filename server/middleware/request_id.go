@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID reuses the caller's X-Request-ID when present, otherwise it
+// generates one, and echoes it back on the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestId := c.GetHeader(RequestIDHeader)
+		if requestId == "" || len(requestId) > 128 {
+			requestId = bson.NewObjectID().Hex()
+		}
+		c.Set("requestId", requestId)
+		c.Header(RequestIDHeader, requestId)
+		c.Next()
+	}
+}
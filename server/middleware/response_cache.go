@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/cache"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// CachePolicy says how long a response may be reused, by the server and by
+// clients.
+type CachePolicy struct {
+	// TTL is how long the server keeps a response.
+	TTL time.Duration
+	// MaxAge is how long browsers and proxies may reuse a response before
+	// revalidating it. Zero makes them revalidate every time.
+	MaxAge time.Duration
+	// Private keeps shared proxies from storing responses that are only
+	// served to authenticated callers.
+	Private bool
+}
+
+func (p CachePolicy) header() string {
+	visibility := "public"
+	if p.Private {
+		visibility = "private"
+	}
+	if p.MaxAge <= 0 {
+		return visibility + ", no-cache"
+	}
+	return fmt.Sprintf("%s, max-age=%d", visibility, int(p.MaxAge.Seconds()))
+}
+
+// CacheResponse serves successful GET responses from store, keyed by prefix
+// and the request URL. Every response it passes gets an ETag and a
+// Cache-Control header, and a request whose If-None-Match matches the ETag
+// is answered with 304 Not Modified.
+func CacheResponse(store cache.Cache, prefix string, policy CachePolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+		ctx := c.Request.Context()
+		key := prefix + c.Request.URL.Path + "?" + c.Request.URL.Query().Encode()
+		data, ok, err := store.Get(ctx, key)
+		if err != nil {
+			logging.FromContext(c, logger).Warn("response cache lookup failed", "key", key, "error", err)
+		}
+		if ok {
+			if etag, contentType, body, valid := decodeCached(data); valid {
+				metrics.ResponseCache.WithLabelValues(prefix, "hit").Inc()
+				writeCacheable(c, policy, etag, contentType, body)
+				c.Abort()
+				return
+			}
+		}
+		metrics.ResponseCache.WithLabelValues(prefix, "miss").Inc()
+
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.status != http.StatusOK {
+			c.Writer.WriteHeader(w.status)
+			c.Writer.WriteHeaderNow()
+			_, _ = c.Writer.Write(w.body.Bytes())
+			return
+		}
+		body := w.body.Bytes()
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		contentType := c.Writer.Header().Get("Content-Type")
+		if err := store.Set(ctx, key, encodeCached(etag, contentType, body), policy.TTL); err != nil {
+			logging.FromContext(c, logger).Warn("response cache store failed", "key", key, "error", err)
+		}
+		writeCacheable(c, policy, etag, contentType, body)
+	}
+}
+
+func writeCacheable(c *gin.Context, policy CachePolicy, etag, contentType string, body []byte) {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", policy.header())
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// etagMatches applies the weak comparison If-None-Match calls for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// A cached response is stored as its ETag, content type and body separated
+// by newlines. Neither header value can contain a newline.
+func encodeCached(etag, contentType string, body []byte) []byte {
+	data := make([]byte, 0, len(etag)+len(contentType)+len(body)+2)
+	data = append(data, etag...)
+	data = append(data, '\n')
+	data = append(data, contentType...)
+	data = append(data, '\n')
+	return append(data, body...)
+}
+
+func decodeCached(data []byte) (etag, contentType string, body []byte, ok bool) {
+	etagPart, rest, ok := bytes.Cut(data, []byte{'\n'})
+	if !ok {
+		return "", "", nil, false
+	}
+	typePart, body, ok := bytes.Cut(rest, []byte{'\n'})
+	if !ok {
+		return "", "", nil, false
+	}
+	return string(etagPart), string(typePart), body, true
+}
+
+// bufferedWriter holds the handler's response so its ETag can be set
+// before anything is sent.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) { w.status = code }
+func (w *bufferedWriter) WriteHeaderNow()      {}
+func (w *bufferedWriter) Status() int          { return w.status }
+func (w *bufferedWriter) Size() int            { return w.body.Len() }
+func (w *bufferedWriter) Written() bool        { return w.body.Len() > 0 }
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
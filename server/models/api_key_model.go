@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Scopes an API key can be granted. Session logins are not scoped.
+const (
+	ScopeMoviesRead       = "movies:read"
+	ScopeMoviesWrite      = "movies:write"
+	ScopeGenresWrite      = "genres:write"
+	ScopeReviewsWrite     = "reviews:write"
+	ScopeUsersWrite       = "users:write"
+	ScopeDataExport       = "data:export"
+	ScopeDataImport       = "data:import"
+	ScopeCollectionsWrite = "collections:write"
+	ScopeListsRead        = "lists:read"
+	ScopeListsWrite       = "lists:write"
+	ScopeAnalyticsRead    = "analytics:read"
+)
+
+// APIKey is a long-lived credential for automation. Only the SHA-256 hash of
+// the key is stored; Prefix is kept so users can tell their keys apart.
+type APIKey struct {
+	ID         bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	KeyID      string        `bson:"key_id" json:"key_id"`
+	UserID     string        `bson:"user_id" json:"user_id"`
+	Name       string        `bson:"name" json:"name"`
+	Prefix     string        `bson:"prefix" json:"prefix"`
+	KeyHash    string        `bson:"key_hash" json:"-"`
+	Scopes     []string      `bson:"scopes" json:"scopes"`
+	ExpiresAt  *time.Time    `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time    `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time    `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time     `bson:"created_at" json:"created_at"`
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,min=2,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=movies:read movies:write genres:write reviews:write users:write data:export data:import collections:write lists:read lists:write analytics:read"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"gte=0,lte=365"`
+}
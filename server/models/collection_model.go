@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const (
+	CollectionManual = "manual"
+	CollectionSmart  = "smart"
+)
+
+// CuratedCollection is an editorial list shown to every user. Manual
+// collections hold an ordered list of movies; smart collections select
+// movies with Rules whenever they are viewed.
+type CuratedCollection struct {
+	ID           bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	CollectionID string        `bson:"collection_id" json:"collection_id"`
+	Name         string        `bson:"name" json:"name"`
+	Description  string        `bson:"description" json:"description"`
+	Kind         string        `bson:"kind" json:"kind"`
+	ImdbIDs      []string      `bson:"imdb_ids" json:"imdb_ids,omitempty"`
+	Rules        *MovieQuery   `bson:"rules,omitempty" json:"rules,omitempty"`
+	// Limit caps how many movies a smart collection shows.
+	Limit int `bson:"limit,omitempty" json:"limit,omitempty"`
+	// Position orders collections; lower comes first.
+	Position int `bson:"position" json:"position"`
+	// The collection is only listed between VisibleFrom and VisibleUntil,
+	// when they are set.
+	VisibleFrom  *time.Time `bson:"visible_from,omitempty" json:"visible_from,omitempty"`
+	VisibleUntil *time.Time `bson:"visible_until,omitempty" json:"visible_until,omitempty"`
+	CreatedBy    string     `bson:"created_by" json:"-"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `bson:"updated_at" json:"updated_at"`
+}
+
+type CuratedCollectionRequest struct {
+	Name         string      `json:"name" validate:"required,min=2,max=100"`
+	Description  string      `json:"description" validate:"max=1000"`
+	Kind         string      `json:"kind" validate:"required,oneof=manual smart"`
+	ImdbIDs      []string    `json:"imdb_ids" validate:"max=500,unique,dive,required"`
+	Rules        *MovieQuery `json:"rules" validate:"required_if=Kind smart,omitempty"`
+	Limit        int         `json:"limit" validate:"gte=0,lte=100"`
+	Position     int         `json:"position"`
+	VisibleFrom  *time.Time  `json:"visible_from"`
+	VisibleUntil *time.Time  `json:"visible_until"`
+}
+
+// CuratedCollectionResponse is a collection with its movies in display order.
+type CuratedCollectionResponse struct {
+	CuratedCollection
+	Movies []Movie `json:"movies"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// FeedRail is one row of the home page.
+type FeedRail struct {
+	ID     string  `json:"id"`
+	Kind   string  `json:"kind"`
+	Title  string  `json:"title"`
+	Movies []Movie `json:"movies"`
+	// Progress holds the resume position in seconds, by IMDb ID, on the
+	// continue watching rail.
+	Progress map[string]int `json:"progress,omitempty"`
+}
+
+type FeedResponse struct {
+	Rails       []FeedRail `json:"rails"`
+	GeneratedAt time.Time  `json:"generated_at"`
+}
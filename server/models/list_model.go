@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Who can see a user list. Unlisted lists are reachable by anyone holding
+// the share token; public lists can also be browsed and followed.
+const (
+	ListPrivate  = "private"
+	ListUnlisted = "unlisted"
+	ListPublic   = "public"
+)
+
+// UserList is a list of movies owned by a user, in the order they chose.
+type UserList struct {
+	ID          bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	ListID      string        `bson:"list_id" json:"list_id"`
+	OwnerID     string        `bson:"owner_id" json:"owner_id"`
+	Title       string        `bson:"title" json:"title"`
+	Description string        `bson:"description" json:"description"`
+	Privacy     string        `bson:"privacy" json:"privacy"`
+	ImdbIDs     []string      `bson:"imdb_ids" json:"imdb_ids"`
+	// ShareToken is only shown to the owner.
+	ShareToken    string    `bson:"share_token,omitempty" json:"share_token,omitempty"`
+	FollowerCount int       `bson:"follower_count" json:"follower_count"`
+	ClonedFrom    string    `bson:"cloned_from,omitempty" json:"cloned_from,omitempty"`
+	CreatedAt     time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ListFollow records that a user follows someone else's public list.
+type ListFollow struct {
+	ListID     string    `bson:"list_id" json:"list_id"`
+	UserID     string    `bson:"user_id" json:"user_id"`
+	FollowedAt time.Time `bson:"followed_at" json:"followed_at"`
+}
+
+type CreateListRequest struct {
+	Title       string   `json:"title" validate:"required,min=1,max=100"`
+	Description string   `json:"description" validate:"max=1000"`
+	Privacy     string   `json:"privacy" validate:"omitempty,oneof=private unlisted public"`
+	ImdbIDs     []string `json:"imdb_ids" validate:"max=1000,unique,dive,required"`
+}
+
+// UpdateListRequest changes only the fields that are sent.
+type UpdateListRequest struct {
+	Title       *string `json:"title" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+	Privacy     *string `json:"privacy" validate:"omitempty,oneof=private unlisted public"`
+}
+
+// UserListResponse is a list with summaries of its movies in list order.
+type UserListResponse struct {
+	UserList
+	Movies []MovieSummary `json:"movies"`
+}
@@ -4,7 +4,7 @@ import "go.mongodb.org/mongo-driver/v2/bson"
 
 type Genre struct {
 	GenreID   int    `bson:"genre_id" json:"genre_id" validate:"required"`
-	GenreName string `bson:"genre_name" json:"genre_name" validate:"required, min=2, max=100"`
+	GenreName string `bson:"genre_name" json:"genre_name" validate:"required,min=2,max=100"`
 }
 
 type Ranking struct {
@@ -12,13 +12,70 @@ type Ranking struct {
 	RankingName  string `bson:"ranking_name" json:"ranking_name" validate:"required"`
 }
 
+// NotRanked is the ranking of a movie that has no admin review yet.
+var NotRanked = Ranking{RankingValue: 999, RankingName: "Not_Ranked"}
+
+// CastMember is an actor's credit on a movie.
+type CastMember struct {
+	PersonID string `bson:"person_id,omitempty" json:"person_id,omitempty"`
+	// ProviderID is the metadata provider's ID for the person, which tells
+	// apart people who share a name.
+	ProviderID string `bson:"provider_id,omitempty" json:"provider_id,omitempty" validate:"max=50"`
+	Name       string `bson:"name" json:"name" validate:"required,max=200"`
+	Character  string `bson:"character" json:"character" validate:"max=200"`
+}
+
+// CrewMember is a behind-the-camera credit, e.g. Job "Director".
+type CrewMember struct {
+	PersonID   string `bson:"person_id,omitempty" json:"person_id,omitempty"`
+	ProviderID string `bson:"provider_id,omitempty" json:"provider_id,omitempty" validate:"max=50"`
+	Name       string `bson:"name" json:"name" validate:"required,max=200"`
+	Job        string `bson:"job" json:"job" validate:"required,max=100"`
+}
+
 type Movie struct {
 	ID          bson.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
 	ImdbID      string        `bson:"imdb_id" json:"imdb_id" validate:"required"`
-	Title       string        `bson:"title" json:"title" validate:"required, min=2, max = 500"`
-	PosterPath  string        `bson:"poster_path" json:"poster_path" validate:"required, url"`
+	Title       string        `bson:"title" json:"title" validate:"required,min=2,max=500"`
+	PosterPath  string        `bson:"poster_path" json:"poster_path" validate:"required,url"`
 	YouTubeID   string        `bson:"youtube_id" json:"youtube_id" validate:"required"`
-	Genre       []Genre       `bson:"genre" json:"genre" validate:"required, dive"`
+	Genre       []Genre       `bson:"genre" json:"genre" validate:"required,dive"`
 	AdminReview string        `bson:"admin_review" json:"admin_review"`
 	Ranking     Ranking       `bson:"ranking" json:"ranking" validate:"required"`
+	// ReleaseDate is stored as YYYY-MM-DD so range queries can compare strings.
+	ReleaseDate     string       `bson:"release_date" json:"release_date" validate:"omitempty,datetime=2006-01-02"`
+	RuntimeMinutes  int          `bson:"runtime_minutes" json:"runtime_minutes" validate:"gte=0,lte=1000"`
+	Synopsis        string       `bson:"synopsis" json:"synopsis" validate:"max=5000"`
+	Cast            []CastMember `bson:"cast" json:"cast" validate:"dive"`
+	Crew            []CrewMember `bson:"crew" json:"crew" validate:"dive"`
+	SpokenLanguages []string     `bson:"spoken_languages" json:"spoken_languages" validate:"dive,len=2,lowercase"`
+	Country         string       `bson:"country" json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	Certification   string       `bson:"certification" json:"certification" validate:"max=10"`
+}
+
+// MovieSummary is the short form of a movie used in lists.
+type MovieSummary struct {
+	ImdbID      string `bson:"imdb_id" json:"imdb_id"`
+	Title       string `bson:"title" json:"title"`
+	PosterPath  string `bson:"poster_path" json:"poster_path"`
+	ReleaseDate string `bson:"release_date" json:"release_date"`
+}
+
+// MovieResponse is a movie with related titles for its detail page.
+type MovieResponse struct {
+	Movie
+	MoreFromDirector []MovieSummary `json:"more_from_director"`
+}
+
+// MovieQuery narrows down the catalogue. Unset fields do not filter.
+type MovieQuery struct {
+	Genres        []string `bson:"genres,omitempty" json:"genres,omitempty" validate:"dive,min=2,max=100"`
+	YearFrom      *int     `bson:"year_from,omitempty" json:"year_from,omitempty" validate:"omitempty,gte=1870,lte=2200"`
+	YearTo        *int     `bson:"year_to,omitempty" json:"year_to,omitempty" validate:"omitempty,gte=1870,lte=2200"`
+	RuntimeMin    *int     `bson:"runtime_min,omitempty" json:"runtime_min,omitempty" validate:"omitempty,gte=0"`
+	RuntimeMax    *int     `bson:"runtime_max,omitempty" json:"runtime_max,omitempty" validate:"omitempty,gte=0"`
+	RankingMax    *int     `bson:"ranking_max,omitempty" json:"ranking_max,omitempty" validate:"omitempty,gte=1"`
+	Language      string   `bson:"language,omitempty" json:"language,omitempty"`
+	Country       string   `bson:"country,omitempty" json:"country,omitempty"`
+	Certification string   `bson:"certification,omitempty" json:"certification,omitempty"`
 }
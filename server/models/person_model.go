@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Person is an actor or crew member credited on one or more movies. Movies
+// reference people by PersonID; the filmography is derived from those credits.
+type Person struct {
+	ID       bson.ObjectID `bson:"_id,omitempty" json:"-"`
+	PersonID string        `bson:"person_id" json:"person_id"`
+	Name     string        `bson:"name" json:"name"`
+	// ProviderID is the metadata provider's ID for the person. Credits that
+	// carry one are matched on it, so namesakes stay separate people.
+	ProviderID string `bson:"provider_id,omitempty" json:"provider_id,omitempty"`
+	// NameKey is the lower-cased name that credits without a ProviderID are
+	// matched on.
+	NameKey   string    `bson:"name_key" json:"-"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// FilmographyEntry is one movie a person worked on and what they did on it.
+type FilmographyEntry struct {
+	MovieSummary
+	Characters []string `json:"characters,omitempty"`
+	Jobs       []string `json:"jobs,omitempty"`
+}
+
+type PersonResponse struct {
+	Person
+	Filmography []FilmographyEntry `json:"filmography"`
+}
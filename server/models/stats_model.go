@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// MovieStatsBucket counts what happened to a movie during one hour or one
+// day, starting at Start.
+type MovieStatsBucket struct {
+	ImdbID      string    `bson:"imdb_id" json:"imdb_id"`
+	Start       time.Time `bson:"start" json:"start"`
+	Views       int       `bson:"views" json:"views"`
+	Plays       int       `bson:"plays" json:"plays"`
+	Completions int       `bson:"completions" json:"completions"`
+	ListAdds    int       `bson:"list_adds" json:"list_adds"`
+}
+
+// TrendingEntry is a movie's precomputed popularity over a window. Counts
+// are the raw totals for the window; Score weighs and decays them.
+type TrendingEntry struct {
+	Window      string    `bson:"window" json:"-"`
+	ImdbID      string    `bson:"imdb_id" json:"-"`
+	GenreIDs    []int     `bson:"genre_ids" json:"-"`
+	Score       float64   `bson:"score" json:"score"`
+	Views       int       `bson:"views" json:"views"`
+	Plays       int       `bson:"plays" json:"plays"`
+	Completions int       `bson:"completions" json:"completions"`
+	ListAdds    int       `bson:"list_adds" json:"list_adds"`
+	ComputedAt  time.Time `bson:"computed_at" json:"-"`
+}
+
+type TrendingMovie struct {
+	Movie
+	TrendingEntry
+}
+
+type TrendingResponse struct {
+	Window     string          `json:"window"`
+	ComputedAt time.Time       `json:"computed_at"`
+	Movies     []TrendingMovie `json:"movies"`
+}
@@ -9,8 +9,8 @@ import (
 type User struct {
 	ID              bson.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
 	UserID          string        `bson:"user_id" json:"user_id" validate:"required"`
-	FirstName       string        `bson:"first_name" json:"first_name" validate:"required, min=2, max=100"`
-	LastName        string        `bson:"last_name" json:"last_name" validate:"required, min=1, max=100"`
+	FirstName       string        `bson:"first_name" json:"first_name" validate:"required,min=2,max=100"`
+	LastName        string        `bson:"last_name" json:"last_name" validate:"required,min=1,max=100"`
 	Email           string        `bson:"email" json:"email" validate:"required,email"`
 	Password        string        `bson:"password" json:"password" validate:"required,min=6"`
 	Role            string        `bson:"role" json:"role" validate:"required,oneof=admin user"`
@@ -19,11 +19,32 @@ type User struct {
 	Token           string        `bson:"token" json:"token"`
 	RefreshToken    string        `bson:"refresh_token" json:"refresh_token"`
 	FavouriteGenres []Genre       `bson:"favourite_genres" json:"favourite_genres" validate:"dive"`
+	// Lockout and verification state is managed by the server and never bound from requests.
+	FailedLoginAttempts int                `bson:"failed_login_attempts" json:"-"`
+	LockedUntil         time.Time          `bson:"locked_until,omitempty" json:"-"`
+	EmailVerified       bool               `bson:"email_verified" json:"-"`
+	VerificationSentAt  time.Time          `bson:"verification_sent_at,omitempty" json:"-"`
+	Identities          []ExternalIdentity `bson:"identities,omitempty" json:"-"`
+	MFAEnabled          bool               `bson:"mfa_enabled" json:"-"`
+	MFASecret           string             `bson:"mfa_secret,omitempty" json:"-"`
+	MFAPendingSecret    string             `bson:"mfa_pending_secret,omitempty" json:"-"`
+	MFALastStep         int64              `bson:"mfa_last_step,omitempty" json:"-"`
+	RecoveryCodeHashes  []string           `bson:"recovery_code_hashes,omitempty" json:"-"`
+	SessionVersion      int                `bson:"session_version" json:"-"`
+}
+
+// ExternalIdentity links a user to an account at an OIDC provider.
+type ExternalIdentity struct {
+	Provider string    `bson:"provider" json:"provider"`
+	Subject  string    `bson:"subject" json:"subject"`
+	LinkedAt time.Time `bson:"linked_at" json:"linked_at"`
 }
 
 type UserLogin struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+	// ReturnTokens asks for the tokens in the response body instead of cookies.
+	ReturnTokens bool `json:"return_tokens"`
 }
 
 // DTO
@@ -36,4 +57,15 @@ type UserResponse struct {
 	Token           string  `json:"token"`
 	RefreshToken    string  `json:"refresh_token"`
 	FavouriteGenres []Genre `json:"favourite_genres"`
+	EmailVerified   bool    `json:"email_verified"`
+	MFAEnabled      bool    `json:"mfa_enabled"`
+}
+
+type MFALogin struct {
+	// MFAToken may be left out after an external login, which hands the
+	// challenge over in a cookie instead.
+	MFAToken     string `json:"mfa_token"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code"`
+	ReturnTokens bool   `json:"return_tokens"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// WatchProgress is how far a user has got through a movie.
+type WatchProgress struct {
+	UserID          string    `bson:"user_id" json:"-"`
+	ImdbID          string    `bson:"imdb_id" json:"imdb_id"`
+	PositionSeconds int       `bson:"position_seconds" json:"position_seconds"`
+	Completed       bool      `bson:"completed" json:"completed"`
+	UpdatedAt       time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type WatchProgressRequest struct {
+	PositionSeconds int  `json:"position_seconds" validate:"gte=0,lte=86400"`
+	Completed       bool `json:"completed"`
+}
@@ -2,16 +2,78 @@ package routes
 
 import (
 	controller "github.com/Tarun-Kataruka/MagicStreamMovies/server/controllers"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
 	"github.com/gin-gonic/gin"
 
 	verify "github.com/Tarun-Kataruka/MagicStreamMovies/server/middleware"
 )
 
 func SetUpProctectedRoutes(router *gin.Engine) {
-	router.Use(verify.AuthMiddleware())
+	// Routes on sessions refuse API keys. A route is only reachable with a key
+	// when it is registered on a group naming the scope the key must have.
+	sessions := router.Group("", verify.AuthMiddleware(), verify.CSRFProtect())
+	keyAccessible := func(scope string) *gin.RouterGroup {
+		return router.Group("", verify.APIKeyScope(scope), verify.AuthMiddleware(), verify.CSRFProtect())
+	}
+	moviesRead := keyAccessible(models.ScopeMoviesRead)
+	moviesWrite := keyAccessible(models.ScopeMoviesWrite)
+	reviewsWrite := keyAccessible(models.ScopeReviewsWrite)
+	genresWrite := keyAccessible(models.ScopeGenresWrite)
+	usersWrite := keyAccessible(models.ScopeUsersWrite)
+	dataExport := keyAccessible(models.ScopeDataExport)
+	dataImport := keyAccessible(models.ScopeDataImport)
+	analyticsRead := keyAccessible(models.ScopeAnalyticsRead)
+	collectionsWrite := keyAccessible(models.ScopeCollectionsWrite)
+	listsWrite := keyAccessible(models.ScopeListsWrite)
+	listsRead := keyAccessible(models.ScopeListsRead)
 
-	router.GET("/movie/:imdb_id", controller.GetMovie())
-	router.POST("/addmovie", controller.AddMovie())
-	router.GET("/recommendedmovies", controller.GetRecommendedMovies())
-	router.PATCH("/updatemovie/:imdb_id", controller.AdminReviewUpdate())
+	moviesRead.GET("/movie/:imdb_id", controller.CountMovieView(), controller.CacheCatalogue(true), controller.GetMovie())
+	moviesWrite.POST("/addmovie", controller.AddMovie())
+	moviesWrite.DELETE("/movie/:imdb_id", controller.DeleteMovie())
+	moviesRead.GET("/people", controller.SearchPeople())
+	moviesRead.GET("/people/:person_id", controller.GetPerson())
+	moviesRead.GET("/recommendedmovies", controller.GetRecommendedMovies())
+	moviesRead.GET("/feed", controller.GetFeed())
+	sessions.GET("/movie/:imdb_id/progress", controller.GetWatchProgress())
+	sessions.PUT("/movie/:imdb_id/progress", controller.UpdateWatchProgress())
+	moviesWrite.GET("/metadata/:imdb_id", controller.GetMovieMetadata())
+	moviesWrite.POST("/movie/:imdb_id/refresh", controller.RefreshMovieMetadata())
+	reviewsWrite.PATCH("/updatemovie/:imdb_id", controller.AdminReviewUpdate())
+	genresWrite.POST("/genres", controller.AddGenre())
+	usersWrite.PATCH("/users/:user_id/unlock", controller.UnlockUser())
+	dataExport.GET("/admin/export/:collection", controller.ExportCollection())
+	dataImport.POST("/admin/import/:collection", controller.ImportCollection())
+	analyticsRead.GET("/admin/analytics/:report", controller.GetAnalyticsReport())
+
+	collectionsWrite.GET("/admin/collections", controller.ListAllCollections())
+	collectionsWrite.POST("/admin/collections", controller.CreateCollection())
+	collectionsWrite.PUT("/admin/collections/order", controller.ReorderCollections())
+	collectionsWrite.PUT("/admin/collections/:collection_id", controller.UpdateCollection())
+	collectionsWrite.PUT("/admin/collections/:collection_id/movies", controller.SetCollectionMovies())
+	collectionsWrite.DELETE("/admin/collections/:collection_id", controller.DeleteCollection())
+
+	listsWrite.POST("/lists", controller.CreateList())
+	listsRead.GET("/lists", controller.ListMyLists())
+	listsRead.GET("/lists/public", controller.SearchPublicLists())
+	listsRead.GET("/lists/following", controller.ListFollowedLists())
+	listsRead.GET("/users/:user_id/lists", controller.ListUserPublicLists())
+	listsRead.GET("/lists/:list_id", controller.GetList())
+	listsWrite.PATCH("/lists/:list_id", controller.UpdateList())
+	listsWrite.DELETE("/lists/:list_id", controller.DeleteList())
+	listsWrite.POST("/lists/:list_id/share", controller.RotateListShareToken())
+	listsWrite.PUT("/lists/:list_id/movies", controller.SetListMovies())
+	listsWrite.POST("/lists/:list_id/movies", controller.AddListMovie())
+	listsWrite.DELETE("/lists/:list_id/movies/:imdb_id", controller.RemoveListMovie())
+	listsWrite.POST("/lists/:list_id/follow", controller.FollowList())
+	listsWrite.DELETE("/lists/:list_id/follow", controller.UnfollowList())
+	listsWrite.POST("/lists/:list_id/clone", controller.CloneList())
+	listsWrite.POST("/shared/lists/:share_token/clone", controller.CloneSharedList())
+
+	sessions.POST("/mfa/enroll", controller.MFAEnroll())
+	sessions.POST("/mfa/confirm", controller.MFAConfirm())
+	sessions.POST("/mfa/disable", controller.MFADisable())
+
+	sessions.POST("/apikeys", controller.CreateAPIKey())
+	sessions.GET("/apikeys", controller.ListAPIKeys())
+	sessions.DELETE("/apikeys/:key_id", controller.RevokeAPIKey())
 }
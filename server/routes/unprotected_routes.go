@@ -1,16 +1,48 @@
 package routes
 
 import (
+	"os"
+	"time"
+
 	controller "github.com/Tarun-Kataruka/MagicStreamMovies/server/controllers"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/metrics"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 func SetUpUnProctectedRoutes(router *gin.Engine) {
+	limits := middleware.NewMemoryRateLimitStore()
+	loginByIP := middleware.RateLimit(limits, "login_ip", middleware.LimitFromEnv("LOGIN_IP_RATE_LIMIT", middleware.Limit{Requests: 20, Period: time.Minute}), middleware.ByIP)
+	loginByEmail := middleware.RateLimit(limits, "login_email", middleware.LimitFromEnv("LOGIN_EMAIL_RATE_LIMIT", middleware.Limit{Requests: 5, Period: time.Minute}), middleware.ByEmail)
+	registerByIP := middleware.RateLimit(limits, "register_ip", middleware.LimitFromEnv("REGISTER_IP_RATE_LIMIT", middleware.Limit{Requests: 5, Period: time.Hour}), middleware.ByIP)
+	registerByEmail := middleware.RateLimit(limits, "register_email", middleware.LimitFromEnv("REGISTER_EMAIL_RATE_LIMIT", middleware.Limit{Requests: 3, Period: time.Hour}), middleware.ByEmail)
+	forgotByIP := middleware.RateLimit(limits, "forgot_ip", middleware.LimitFromEnv("FORGOT_PASSWORD_IP_RATE_LIMIT", middleware.Limit{Requests: 10, Period: time.Hour}), middleware.ByIP)
+	forgotByEmail := middleware.RateLimit(limits, "forgot_email", middleware.LimitFromEnv("FORGOT_PASSWORD_EMAIL_RATE_LIMIT", middleware.Limit{Requests: 3, Period: time.Hour}), middleware.ByEmail)
+	resendByIP := middleware.RateLimit(limits, "verify_resend_ip", middleware.LimitFromEnv("VERIFY_RESEND_IP_RATE_LIMIT", middleware.Limit{Requests: 10, Period: time.Hour}), middleware.ByIP)
+	resetByIP := middleware.RateLimit(limits, "reset_ip", middleware.LimitFromEnv("RESET_PASSWORD_IP_RATE_LIMIT", middleware.Limit{Requests: 10, Period: time.Hour}), middleware.ByIP)
 
-	router.POST("/register", controller.RegisterUser())
-	router.POST("/login", controller.LoginUser())
-	router.POST("/logout", controller. LogoutHandler())
-	router.GET("/movies", controller.GetMovies())
-	router.GET("/genres", controller.GetGenres())
-	router.POST("/refresh", controller.RefreshTokenHandler())
+	router.POST("/register", registerByIP, registerByEmail, controller.RegisterUser())
+	router.POST("/login", loginByIP, loginByEmail, controller.LoginUser())
+	router.POST("/login/mfa", loginByIP, controller.LoginMFA())
+	router.POST("/logout", middleware.CSRFProtect(), controller.LogoutHandler())
+	router.GET("/movies", controller.CacheCatalogue(false), controller.GetMovies())
+	router.GET("/movies/trending", controller.GetTrendingMovies())
+	router.GET("/genres", controller.CacheCatalogue(false), controller.GetGenres())
+	router.GET("/genres/:id/top", controller.GetGenreTopMovies())
+	router.GET("/collections", controller.ListCollections())
+	router.GET("/collections/:collection_id", controller.GetCollection())
+	router.GET("/shared/lists/:share_token", controller.GetSharedList())
+	router.POST("/refresh", middleware.CSRFProtect(), controller.RefreshTokenHandler())
+	router.POST("/password/forgot", forgotByIP, forgotByEmail, controller.ForgotPassword())
+	router.POST("/password/reset", resetByIP, controller.ResetPassword())
+	router.GET("/verify-email", controller.VerifyEmail())
+	router.POST("/verify-email/resend", resendByIP, controller.ResendVerificationEmail())
+	router.GET("/auth/oidc/login", controller.OIDCLogin())
+	router.GET("/auth/oidc/callback", controller.OIDCCallback())
+	// METRICS_ADDR serves metrics on an internal listener instead; the public
+	// one only serves them to scrapers holding METRICS_TOKEN.
+	if token := os.Getenv("METRICS_TOKEN"); token != "" {
+		router.GET("/metrics", metrics.Handler(token))
+	}
+	router.GET("/.well-known/jwks.json", controller.GetJWKS())
 }
@@ -0,0 +1,244 @@
+// Package seed loads the development fixtures in data/fixtures into MongoDB.
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-yaml"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// FixtureVersion is the fixture file format this loader understands. Bump it
+// when a fixture's shape changes incompatibly.
+const FixtureVersion = 1
+
+// fixtureFile is the envelope shared by every fixture file.
+type fixtureFile[T any] struct {
+	Version int `json:"version"`
+	Items   []T `json:"items"`
+}
+
+// userFixture carries a plain text password, which is hashed on load.
+type userFixture struct {
+	FirstName       string         `json:"first_name" validate:"required,min=2,max=100"`
+	LastName        string         `json:"last_name" validate:"required,min=1,max=100"`
+	Email           string         `json:"email" validate:"required,email"`
+	Password        string         `json:"password" validate:"required,min=6"`
+	Role            string         `json:"role" validate:"required,oneof=admin user"`
+	FavouriteGenres []models.Genre `json:"favourite_genres" validate:"dive"`
+}
+
+// Result counts the documents inserted and updated per collection.
+type Result struct {
+	Collection string
+	Inserted   int64
+	Updated    int64
+}
+
+var validate = validator.New()
+
+// Load upserts every fixture in dir by its natural key, so running it again
+// leaves the database unchanged. With reset, the seeded collections are
+// emptied first; their indexes are kept.
+func Load(ctx context.Context, dir string, reset bool) ([]Result, error) {
+	if reset {
+		for _, collection := range []*mongo.Collection{database.Genres, database.Rankings, database.Movies, database.People, database.Users} {
+			if _, err := collection.DeleteMany(ctx, bson.M{}); err != nil {
+				return nil, fmt.Errorf("reset %s: %w", collection.Name(), err)
+			}
+		}
+	}
+
+	genres, err := readFixture[models.Genre](dir, "genres")
+	if err != nil {
+		return nil, err
+	}
+	rankings, err := readFixture[models.Ranking](dir, "rankings")
+	if err != nil {
+		return nil, err
+	}
+	movies, err := readFixture[models.Movie](dir, "movies")
+	if err != nil {
+		return nil, err
+	}
+	users, err := readFixture[userFixture](dir, "users")
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range movies {
+		if err := database.LinkPeople(ctx, &movies[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []Result
+	result, err := upsertAll(ctx, database.Genres, genres, func(g models.Genre) (bson.M, bson.M, bson.M) {
+		return bson.M{"genre_id": g.GenreID}, bson.M{"genre_name": g.GenreName}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, result)
+
+	result, err = upsertAll(ctx, database.Rankings, rankings, func(r models.Ranking) (bson.M, bson.M, bson.M) {
+		return bson.M{"ranking_value": r.RankingValue}, bson.M{"ranking_name": r.RankingName}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, result)
+
+	result, err = upsertAll(ctx, database.Movies, movies, func(m models.Movie) (bson.M, bson.M, bson.M) {
+		return bson.M{"imdb_id": m.ImdbID}, bson.M{
+			"title":            m.Title,
+			"poster_path":      m.PosterPath,
+			"youtube_id":       m.YouTubeID,
+			"genre":            m.Genre,
+			"admin_review":     m.AdminReview,
+			"ranking":          m.Ranking,
+			"release_date":     m.ReleaseDate,
+			"runtime_minutes":  m.RuntimeMinutes,
+			"synopsis":         m.Synopsis,
+			"cast":             nonNil(m.Cast),
+			"crew":             nonNil(m.Crew),
+			"spoken_languages": nonNil(m.SpokenLanguages),
+			"country":          m.Country,
+			"certification":    m.Certification,
+		}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, result)
+
+	// Passwords are only set when a user is first created, so a developer who
+	// changed theirs keeps it. Hash up front so a bcrypt failure does not
+	// leave users half loaded.
+	hashes := make(map[string]string, len(users))
+	for _, u := range users {
+		hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		hashes[u.Email] = string(hash)
+	}
+	now := time.Now()
+	result, err = upsertAll(ctx, database.Users, users, func(u userFixture) (bson.M, bson.M, bson.M) {
+		return bson.M{"email": u.Email}, bson.M{
+			"first_name":       u.FirstName,
+			"last_name":        u.LastName,
+			"role":             u.Role,
+			"favourite_genres": u.FavouriteGenres,
+			"email_verified":   true,
+			"updated_at":       now,
+		}, bson.M{
+			"user_id":    bson.NewObjectID().Hex(),
+			"password":   hashes[u.Email],
+			"created_at": now,
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, result)
+	return results, nil
+}
+
+// nonNil stores absent lists as empty arrays, matching migrated documents.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+// fixtureExtensions are the formats a fixture may be written in.
+var fixtureExtensions = []string{".json", ".yaml", ".yml"}
+
+// findFixture returns the one file in dir holding the named fixture.
+func findFixture(dir, name string) (string, error) {
+	var found []string
+	for _, ext := range fixtureExtensions {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no %s fixture in %s, expected one of %s", name, dir, strings.Join(fixtureExtensions, ", "))
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%s fixture is defined more than once: %s", name, strings.Join(found, ", "))
+	}
+}
+
+// readFixture decodes and validates the named fixture. YAML is converted to
+// JSON first, so both formats share the models' json field names.
+func readFixture[T any](dir, name string) ([]T, error) {
+	path, err := findFixture(dir, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	var file fixtureFile[T]
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Version != FixtureVersion {
+		return nil, fmt.Errorf("%s: fixture version %d is not supported, expected %d", path, file.Version, FixtureVersion)
+	}
+	for i, item := range file.Items {
+		if err := validate.Struct(item); err != nil {
+			return nil, fmt.Errorf("%s: item %d: %w", path, i, err)
+		}
+	}
+	return file.Items, nil
+}
+
+// upsertAll writes items in one bulk operation. fields returns the natural key
+// filter, the fields to keep in sync and the fields only set on insert.
+func upsertAll[T any](ctx context.Context, collection *mongo.Collection, items []T, fields func(T) (key, set, setOnInsert bson.M)) (Result, error) {
+	result := Result{Collection: collection.Name()}
+	if len(items) == 0 {
+		return result, nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(items))
+	for _, item := range items {
+		key, set, setOnInsert := fields(item)
+		update := bson.M{"$set": set}
+		if setOnInsert != nil {
+			update["$setOnInsert"] = setOnInsert
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(key).SetUpdate(update).SetUpsert(true))
+	}
+	res, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return result, fmt.Errorf("seed %s: %w", collection.Name(), err)
+	}
+	result.Inserted = res.UpsertedCount
+	result.Updated = res.ModifiedCount
+	return result, nil
+}
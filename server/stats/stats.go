@@ -0,0 +1,352 @@
+// Package stats counts what users do with movies and turns the counts into
+// trending scores. It also keeps the daily active users and LLM review
+// classification outcomes that admin analytics report on.
+//
+// Events are counted in memory and written to hourly and daily buckets by a
+// background aggregator, which also recomputes the trending scores, so
+// requests never wait on either. Counts not yet flushed are lost if the
+// process dies.
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/logging"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Events counted per movie. Each is also the name of its bucket field.
+const (
+	EventView       = "views"
+	EventPlay       = "plays"
+	EventCompletion = "completions"
+	EventListAdd    = "list_adds"
+)
+
+// eventWeights says how much each event says about a movie's popularity.
+var eventWeights = map[string]float64{
+	EventView:       1,
+	EventPlay:       3,
+	EventCompletion: 5,
+	EventListAdd:    4,
+}
+
+// Window is a period trending scores are computed over. Events lose half
+// their weight every HalfLife, so recent activity counts most.
+type Window struct {
+	Span     time.Duration
+	HalfLife time.Duration
+}
+
+var Windows = map[string]Window{
+	"24h": {Span: 24 * time.Hour, HalfLife: 6 * time.Hour},
+	"7d":  {Span: 7 * 24 * time.Hour, HalfLife: 48 * time.Hour},
+}
+
+var logger = logging.For("stats")
+
+type bucketKey struct {
+	imdbId string
+	hour   time.Time
+}
+
+// Outcomes of an LLM review classification.
+const (
+	LLMSuccess = "success"
+	LLMFailure = "failure"
+	// LLMUnmatched means the model answered with something that is not a
+	// ranking name.
+	LLMUnmatched = "unmatched"
+)
+
+type activeKey struct {
+	userId string
+	day    time.Time
+}
+
+type llmKey struct {
+	outcome string
+	day     time.Time
+}
+
+var (
+	mu      sync.Mutex
+	pending = map[bucketKey]map[string]int{}
+	active  = map[activeKey]bool{}
+	llm     = map[llmKey]int{}
+)
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// Record counts one event for a movie. It only touches memory.
+func Record(imdbId, event string) {
+	key := bucketKey{imdbId: imdbId, hour: time.Now().UTC().Truncate(time.Hour)}
+	mu.Lock()
+	defer mu.Unlock()
+	counts, ok := pending[key]
+	if !ok {
+		counts = map[string]int{}
+		pending[key] = counts
+	}
+	counts[event]++
+}
+
+// RecordActive notes that a user made an authenticated request today.
+func RecordActive(userId string) {
+	key := activeKey{userId: userId, day: today()}
+	mu.Lock()
+	defer mu.Unlock()
+	active[key] = true
+}
+
+// RecordLLMCall counts the outcome of one review classification.
+func RecordLLMCall(outcome string) {
+	key := llmKey{outcome: outcome, day: today()}
+	mu.Lock()
+	defer mu.Unlock()
+	llm[key]++
+}
+
+// Flush writes everything recorded so far: movie events to the hourly and
+// daily buckets, active users and LLM outcomes to their daily records.
+// Whatever fails to be written is kept for the next flush.
+func Flush(ctx context.Context) error {
+	mu.Lock()
+	batch, activeBatch, llmBatch := pending, active, llm
+	pending, active, llm = map[bucketKey]map[string]int{}, map[activeKey]bool{}, map[llmKey]int{}
+	mu.Unlock()
+	return errors.Join(
+		flushActive(ctx, activeBatch),
+		flushLLM(ctx, llmBatch),
+		flushMovieEvents(ctx, batch),
+	)
+}
+
+// flushActive records each user once per day.
+func flushActive(ctx context.Context, batch map[activeKey]bool) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(batch))
+	for key := range batch {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"user_id": key.userId, "day": key.day}).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{"user_id": key.userId, "day": key.day}}).
+			SetUpsert(true))
+	}
+	if _, err := database.UserActivity.BulkWrite(ctx, writes); err != nil {
+		mu.Lock()
+		for key := range batch {
+			active[key] = true
+		}
+		mu.Unlock()
+		return fmt.Errorf("write user activity: %w", err)
+	}
+	return nil
+}
+
+func flushLLM(ctx context.Context, batch map[llmKey]int) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(batch))
+	for key, n := range batch {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"start": key.day}).
+			SetUpdate(bson.M{"$inc": bson.M{key.outcome: n}}).
+			SetUpsert(true))
+	}
+	if _, err := database.LLMStatsDaily.BulkWrite(ctx, writes); err != nil {
+		mu.Lock()
+		for key, n := range batch {
+			llm[key] += n
+		}
+		mu.Unlock()
+		return fmt.Errorf("write LLM stats: %w", err)
+	}
+	return nil
+}
+
+func flushMovieEvents(ctx context.Context, batch map[bucketKey]map[string]int) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	hourly := make([]mongo.WriteModel, 0, len(batch))
+	daily := make([]mongo.WriteModel, 0, len(batch))
+	for key, counts := range batch {
+		inc := bson.M{}
+		for event, n := range counts {
+			inc[event] = n
+		}
+		hourly = append(hourly, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"imdb_id": key.imdbId, "start": key.hour}).
+			SetUpdate(bson.M{"$inc": inc}).
+			SetUpsert(true))
+		daily = append(daily, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"imdb_id": key.imdbId, "start": key.hour.Truncate(24 * time.Hour)}).
+			SetUpdate(bson.M{"$inc": inc}).
+			SetUpsert(true))
+	}
+	if _, err := database.MovieStatsHourly.BulkWrite(ctx, hourly); err != nil {
+		restore(batch)
+		return fmt.Errorf("write hourly stats: %w", err)
+	}
+	// The hourly buckets are already written, so a retry would count them
+	// twice; the daily totals are allowed to come up short instead.
+	if _, err := database.MovieStatsDaily.BulkWrite(ctx, daily); err != nil {
+		return fmt.Errorf("write daily stats: %w", err)
+	}
+	return nil
+}
+
+func restore(batch map[bucketKey]map[string]int) {
+	mu.Lock()
+	defer mu.Unlock()
+	for key, counts := range batch {
+		current, ok := pending[key]
+		if !ok {
+			pending[key] = counts
+			continue
+		}
+		for event, n := range counts {
+			current[event] += n
+		}
+	}
+}
+
+// ComputeTrending recomputes the scores for the named window from the
+// hourly buckets and replaces the previous results.
+func ComputeTrending(ctx context.Context, name string, now time.Time) error {
+	window, ok := Windows[name]
+	if !ok {
+		return fmt.Errorf("unknown window %q", name)
+	}
+	buckets, err := database.FindAll[models.MovieStatsBucket](ctx, database.MovieStatsHourly,
+		bson.M{"start": bson.M{"$gte": now.Add(-window.Span).Truncate(time.Hour)}})
+	if err != nil {
+		return err
+	}
+	entries := map[string]*models.TrendingEntry{}
+	for _, b := range buckets {
+		entry, ok := entries[b.ImdbID]
+		if !ok {
+			entry = &models.TrendingEntry{Window: name, ImdbID: b.ImdbID, GenreIDs: []int{}, ComputedAt: now}
+			entries[b.ImdbID] = entry
+		}
+		// Age is measured from the middle of the bucket's hour.
+		age := now.Sub(b.Start.Add(30 * time.Minute))
+		if age < 0 {
+			age = 0
+		}
+		decay := math.Exp(-math.Ln2 * age.Hours() / window.HalfLife.Hours())
+		entry.Score += decay * (eventWeights[EventView]*float64(b.Views) +
+			eventWeights[EventPlay]*float64(b.Plays) +
+			eventWeights[EventCompletion]*float64(b.Completions) +
+			eventWeights[EventListAdd]*float64(b.ListAdds))
+		entry.Views += b.Views
+		entry.Plays += b.Plays
+		entry.Completions += b.Completions
+		entry.ListAdds += b.ListAdds
+	}
+
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	movies, err := database.FindAll[models.Movie](ctx, database.Movies, bson.M{"imdb_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"imdb_id": 1, "genre": 1}))
+	if err != nil {
+		return err
+	}
+	docs := make([]any, 0, len(movies))
+	for _, movie := range movies {
+		entry := entries[movie.ImdbID]
+		for _, genre := range movie.Genre {
+			entry.GenreIDs = append(entry.GenreIDs, genre.GenreID)
+		}
+		docs = append(docs, entry)
+	}
+	// Readers only look at the latest run, so the new results replace the
+	// old ones as soon as they are inserted.
+	if len(docs) > 0 {
+		if _, err := database.Trending.InsertMany(ctx, docs); err != nil {
+			return err
+		}
+	}
+	_, err = database.Trending.DeleteMany(ctx, bson.M{"window": name, "computed_at": bson.M{"$lt": now}})
+	return err
+}
+
+// Trending returns the top movies of the latest run for a window, best
+// first, optionally limited to one genre.
+func Trending(ctx context.Context, window string, genreId *int, limit int) ([]models.TrendingEntry, time.Time, error) {
+	var latest models.TrendingEntry
+	err := database.Trending.FindOne(ctx, bson.M{"window": window},
+		options.FindOne().SetSort(bson.D{{Key: "computed_at", Value: -1}})).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return []models.TrendingEntry{}, time.Time{}, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	filter := bson.M{"window": window, "computed_at": latest.ComputedAt}
+	if genreId != nil {
+		filter["genre_ids"] = *genreId
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "score", Value: -1}}).SetLimit(int64(limit))
+	entries, err := database.FindAll[models.TrendingEntry](ctx, database.Trending, filter, opts)
+	return entries, latest.ComputedAt, err
+}
+
+// Aggregate flushes the recorded events and recomputes every window.
+func Aggregate(ctx context.Context) error {
+	if err := Flush(ctx); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for name := range Windows {
+		if err := ComputeTrending(ctx, name, now); err != nil {
+			return fmt.Errorf("trending %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// StartAggregator runs Aggregate every interval until ctx is done. The
+// returned channel is closed once it has stopped; an aggregation cut short
+// keeps its counts in memory, so a final Flush after that saves them.
+func StartAggregator(ctx context.Context, interval time.Duration, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runCtx, cancel := context.WithTimeout(ctx, interval)
+				err := Aggregate(runCtx)
+				cancel()
+				if err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	logger.Info("stats aggregator started", "interval", interval)
+	return done
+}
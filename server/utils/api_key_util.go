@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
+	"github.com/Tarun-Kataruka/MagicStreamMovies/server/models"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyPrefix marks our keys so they are easy to spot in logs and secret scanners.
+const APIKeyPrefix = "msk_"
+
+// lastUsedResolution limits how often using a key writes its last-used time.
+const lastUsedResolution = time.Minute
+
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+// AuthenticateAPIKey resolves a raw key to the key record and its owner,
+// rejecting revoked and expired keys.
+func AuthenticateAPIKey(ctx context.Context, rawKey string) (*models.APIKey, *models.User, error) {
+	now := time.Now()
+	var key models.APIKey
+	err := database.APIKeys.FindOne(ctx, bson.M{
+		"key_hash":   HashOpaqueToken(rawKey),
+		"revoked_at": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}).Decode(&key)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var user models.User
+	opts := options.FindOne().SetProjection(bson.M{"user_id": 1, "role": 1, "email_verified": 1, "mfa_enabled": 1})
+	if err := database.Users.FindOne(ctx, bson.M{"user_id": key.UserID}, opts).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil, ErrInvalidAPIKey
+		}
+		return nil, nil, err
+	}
+	_, err = database.APIKeys.UpdateOne(ctx, bson.M{
+		"_id": key.ID,
+		"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-lastUsedResolution)}},
+		},
+	}, bson.M{"$set": bson.M{"last_used_at": now}})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &key, &user, nil
+}
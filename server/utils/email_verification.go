@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+const emailVerificationTTL = 48 * time.Hour
+
+type VerificationDetails struct {
+	UserID string
+	Email  string
+	jwt.RegisteredClaims
+}
+
+// RequireEmailVerification reports whether unverified users are refused at
+// login and by AuthMiddleware.
+func RequireEmailVerification() bool {
+	return envBool("REQUIRE_EMAIL_VERIFICATION", false)
+}
+
+// GenerateVerificationToken signs a token binding the user to the email
+// address it was sent to, so it stops working if the address changes.
+func GenerateVerificationToken(userId, email string) (string, error) {
+	claims := &VerificationDetails{
+		UserID: userId,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "MagicStreamMovies",
+			Subject:   "email-verification",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(emailVerificationTTL)),
+		},
+	}
+	return Keys.Sign(claims)
+}
+
+func ValidateVerificationToken(signedToken string) (*VerificationDetails, error) {
+	claims := &VerificationDetails{}
+	_, err := jwt.ParseWithClaims(signedToken, claims, Keys.Keyfunc, append(ParserOptions(), jwt.WithSubject("email-verification"))...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
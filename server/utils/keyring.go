@@ -0,0 +1,296 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is one key pair; its kid is the file name it was loaded from.
+type signingKey struct {
+	kid       string
+	method    jwt.SigningMethod
+	private   crypto.Signer
+	createdAt time.Time
+}
+
+// Keyring signs tokens with its newest key and verifies them with any key it
+// still holds, so rotating keys does not invalidate tokens already issued.
+type Keyring struct {
+	mu     sync.RWMutex
+	dir    string
+	alg    string
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+// Keys is the process-wide keyring, set up by InitKeyring at startup.
+var Keys *Keyring
+
+// InitKeyring loads the keyring and fails when it holds no keys, so the server
+// never issues tokens without key material.
+func InitKeyring() error {
+	ring, err := LoadKeyring()
+	if err != nil {
+		return err
+	}
+	if ring.active == nil {
+		return fmt.Errorf("no JWT signing keys found in %s, run the keygen command to create one", ring.dir)
+	}
+	Keys = ring
+	return nil
+}
+
+// LoadKeyring reads the PEM encoded PKCS#8 private keys in JWT_KEY_DIR
+// (default "keys"). New keys use JWT_KEY_ALG, "RS256" (the default) or "EdDSA".
+func LoadKeyring() (*Keyring, error) {
+	dir := os.Getenv("JWT_KEY_DIR")
+	if dir == "" {
+		dir = "keys"
+	}
+	alg := os.Getenv("JWT_KEY_ALG")
+	if alg == "" {
+		alg = jwt.SigningMethodRS256.Alg()
+	}
+	if alg != jwt.SigningMethodRS256.Alg() && alg != jwt.SigningMethodEdDSA.Alg() {
+		return nil, fmt.Errorf("unsupported JWT_KEY_ALG %q", alg)
+	}
+	ring := &Keyring{dir: dir, alg: alg}
+	if err := ring.Reload(); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// Reload re-reads the key directory, picking up keys added by other instances.
+func (k *Keyring) Reload() error {
+	entries, err := os.ReadDir(k.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	keys := map[string]*signingKey{}
+	var active *signingKey
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".pem" {
+			continue
+		}
+		key, err := loadSigningKey(filepath.Join(k.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		keys[key.kid] = key
+		if active == nil || key.createdAt.After(active.createdAt) {
+			active = key
+		}
+	}
+	k.mu.Lock()
+	k.keys, k.active = keys, active
+	k.mu.Unlock()
+	return nil
+}
+
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+	// Generated kids start with their creation time; other files fall back to
+	// the modification time.
+	createdAt, err := time.Parse("20060102T150405Z", strings.SplitN(kid, "-", 2)[0])
+	if err != nil {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		createdAt = info.ModTime()
+	}
+	key := &signingKey{kid: kid, createdAt: createdAt}
+	switch priv := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private = jwt.SigningMethodRS256, priv
+	case ed25519.PrivateKey:
+		key.method, key.private = jwt.SigningMethodEdDSA, priv
+	default:
+		return nil, fmt.Errorf("%s: unsupported key type %T", path, parsed)
+	}
+	return key, nil
+}
+
+// Generate creates a new key, writes it to the key directory and makes it the
+// signing key. It returns the new key's kid.
+func (k *Keyring) Generate() (string, error) {
+	var priv crypto.Signer
+	var err error
+	if k.alg == jwt.SigningMethodEdDSA.Alg() {
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	} else {
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		return "", err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(k.dir, 0o700); err != nil {
+		return "", err
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	kid := time.Now().UTC().Format("20060102T150405Z") + "-" + fmt.Sprintf("%x", suffix)
+	path := filepath.Join(k.dir, kid+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		return "", err
+	}
+	return kid, k.Reload()
+}
+
+// Sign signs claims with the active key and records its kid in the header.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+	if active == nil {
+		return "", errors.New("no active signing key")
+	}
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.private)
+}
+
+// Keyfunc resolves the verification key named by a token's kid header.
+func (k *Keyring) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}
+	return key.private.Public(), nil
+}
+
+// ParserOptions restricts parsing to the asymmetric algorithms we issue.
+func ParserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+}
+
+// Rotate generates a new key once the active one is older than interval and
+// drops keys older than interval+retain. retain should cover the lifetime of
+// the longest-lived token so it can still be verified after rotation.
+func (k *Keyring) Rotate(interval, retain time.Duration) error {
+	if err := k.Reload(); err != nil {
+		return err
+	}
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+	if active == nil || time.Since(active.createdAt) >= interval {
+		if _, err := k.Generate(); err != nil {
+			return err
+		}
+	}
+	k.mu.RLock()
+	var expired []string
+	for kid, key := range k.keys {
+		if key != k.active && time.Since(key.createdAt) > interval+retain {
+			expired = append(expired, kid)
+		}
+	}
+	k.mu.RUnlock()
+	for _, kid := range expired {
+		if err := os.Remove(filepath.Join(k.dir, kid+".pem")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	return k.Reload()
+}
+
+// StartRotation checks every minute whether a rotation is due until ctx is done.
+func (k *Keyring) StartRotation(ctx context.Context, interval, retain time.Duration, onError func(error)) {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.Rotate(interval, retain); err != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS returns the public half of every key that tokens may still be signed with.
+func (k *Keyring) JWKS() []JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	jwks := make([]JWK, 0, len(k.keys))
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.private.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		jwks = append(jwks, jwk)
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].Kid > jwks[j].Kid })
+	return jwks
+}
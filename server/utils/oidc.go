@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCProvider is an external identity provider users can log in with.
+type OIDCProvider struct {
+	Name     string
+	Config   oauth2.Config
+	Verifier *oidc.IDTokenVerifier
+}
+
+var ErrOIDCNotConfigured = errors.New("OIDC login is not configured")
+
+var (
+	oidcMu       sync.Mutex
+	oidcProvider *OIDCProvider
+)
+
+// GetOIDCProvider returns the provider described by the OIDC_* variables.
+// Discovery runs on first use and is retried on the next call if it fails,
+// so an unreachable provider does not stop the server from starting.
+func GetOIDCProvider(ctx context.Context) (*OIDCProvider, error) {
+	oidcMu.Lock()
+	defer oidcMu.Unlock()
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+	issuer := os.Getenv("OIDC_ISSUER_URL")
+	clientId := os.Getenv("OIDC_CLIENT_ID")
+	if issuer == "" || clientId == "" {
+		return nil, ErrOIDCNotConfigured
+	}
+	provider, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, err
+	}
+	scopes := []string{oidc.ScopeOpenID, "profile", "email"}
+	if extra := os.Getenv("OIDC_SCOPES"); extra != "" {
+		scopes = append([]string{oidc.ScopeOpenID}, strings.Split(extra, ",")...)
+	}
+	name := os.Getenv("OIDC_PROVIDER_NAME")
+	if name == "" {
+		name = "oidc"
+	}
+	oidcProvider = &OIDCProvider{
+		Name: name,
+		Config: oauth2.Config{
+			ClientID:     clientId,
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		Verifier: provider.Verifier(&oidc.Config{ClientID: clientId}),
+	}
+	return oidcProvider, nil
+}
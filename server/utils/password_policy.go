@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Breached holds lower-cased passwords known from public breaches.
+	Breached map[string]struct{}
+}
+
+// LoadPasswordPolicy reads the policy from the environment. Every character
+// class is required unless its PASSWORD_REQUIRE_* variable is "false", and
+// BREACHED_PASSWORDS_FILE points at a newline-separated list of passwords to
+// reject, defaulting to the bundled data/breached_passwords.txt when present.
+func LoadPasswordPolicy() (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:     envInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  envBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  envBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		Breached:      map[string]struct{}{},
+	}
+	path := os.Getenv("BREACHED_PASSWORDS_FILE")
+	if path == "" {
+		path = "data/breached_passwords.txt"
+		if _, err := os.Stat(path); err != nil {
+			return policy, nil
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening breached password list: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			policy.Breached[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading breached password list: %w", err)
+	}
+	return policy, nil
+}
+
+// Validate returns an error describing the first rule the password breaks.
+func (p *PasswordPolicy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters long", p.MinLength)
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		return errors.New("password must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		return errors.New("password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		return errors.New("password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		return errors.New("password must contain a symbol")
+	}
+	if _, ok := p.Breached[strings.ToLower(password)]; ok {
+		return errors.New("password has appeared in a data breach, please choose another")
+	}
+	return nil
+}
+
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil {
+		return v
+	}
+	return def
+}
+
+func envBool(name string, def bool) bool {
+	if v, err := strconv.ParseBool(os.Getenv(name)); err == nil {
+		return v
+	}
+	return def
+}
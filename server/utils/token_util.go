@@ -3,14 +3,13 @@ package utils
 import (
 	"context"
 	"errors"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/Tarun-Kataruka/MagicStreamMovies/server/database"
 	"github.com/gin-gonic/gin"
 	jwt "github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/bson"
 )
 
 type SignedDetails struct {
@@ -19,53 +18,74 @@ type SignedDetails struct {
 	LastName  string
 	Role      string
 	UserID    string
+	// EmailVerified lets AuthMiddleware enforce verification without a lookup.
+	EmailVerified bool
+	// MFAVerified records that the session was opened with a second factor.
+	MFAVerified bool
+	// TokenType keeps refresh tokens from being accepted as access tokens now
+	// that both are signed with the same keys.
+	TokenType string
+	// SessionVersion must match the user's when a refresh token is used, so
+	// bumping the stored version ends every session issued before it.
+	SessionVersion int
 	jwt.RegisteredClaims
 }
 
-var SECRET_KEY string = os.Getenv("SECRET_KEY")
-var REFRESH_SECRET_KEY string = os.Getenv("SECRET_REFRESH_KEY")
-var userCollection *mongo.Collection = database.OpenCollection("users")
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
+
+// RefreshTokenLifetime is the longest a token we issue stays valid, and so
+// how long a retired signing key must be kept for verification.
+const RefreshTokenLifetime = 168 * time.Hour // 7 days
 
-func GenerateToken(email, firstName, lastName, role, userId string) (string, string, error) {
+func GenerateToken(email, firstName, lastName, role, userId string, emailVerified, mfaVerified bool, sessionVersion int) (string, string, error) {
 	claims := &SignedDetails{
-		Email:     email,
-		FirstName: firstName,
-		LastName:  lastName,
-		Role:      role,
-		UserID:    userId,
+		Email:          email,
+		FirstName:      firstName,
+		LastName:       lastName,
+		Role:           role,
+		UserID:         userId,
+		EmailVerified:  emailVerified,
+		MFAVerified:    mfaVerified,
+		TokenType:      AccessTokenType,
+		SessionVersion: sessionVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "MagicStreamMovies",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(SECRET_KEY))
+	signedToken, err := Keys.Sign(claims)
 	if err != nil {
 		return "", "", err
 	}
 	refreshClaims := &SignedDetails{
-		Email:     email,
-		FirstName: firstName,
-		LastName:  lastName,
-		Role:      role,
-		UserID:    userId,
+		Email:          email,
+		FirstName:      firstName,
+		LastName:       lastName,
+		Role:           role,
+		UserID:         userId,
+		EmailVerified:  emailVerified,
+		MFAVerified:    mfaVerified,
+		TokenType:      RefreshTokenType,
+		SessionVersion: sessionVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "MagicStreamMovies",
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(168 * time.Hour)), // 7 days
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(RefreshTokenLifetime)),
 		},
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	signedRefreshToken, err := refreshToken.SignedString([]byte(REFRESH_SECRET_KEY))
+	signedRefreshToken, err := Keys.Sign(refreshClaims)
 	if err != nil {
 		return "", "", err
 	}
 	return signedToken, signedRefreshToken, nil
 }
 
-func UpdateAllTokens(userId, token, refreshToken string) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
+func UpdateAllTokens(ctx context.Context, userId, token, refreshToken string) error {
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Second)
 	defer cancel()
 	updatedAt, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	updateData := bson.M{
@@ -75,14 +95,23 @@ func UpdateAllTokens(userId, token, refreshToken string) error {
 			"updated_at":    updatedAt,
 		},
 	}
-	err := userCollection.FindOneAndUpdate(ctx, bson.M{"user_id": userId}, updateData).Err()
+	err := database.Users.FindOneAndUpdate(ctx, bson.M{"user_id": userId}, updateData).Err()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// GetAccessToken prefers an "Authorization: Bearer" header, as used by API and
+// CLI clients, and falls back to the access_token cookie set for browsers.
 func GetAccessToken(c *gin.Context) (string, error) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		scheme, token, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			return "", errors.New("unsupported authorization scheme")
+		}
+		return strings.TrimSpace(token), nil
+	}
 	tokenString, err := c.Cookie("access_token")
 	if err != nil {
 		return "", err
@@ -92,34 +121,24 @@ func GetAccessToken(c *gin.Context) (string, error) {
 
 func ValidateToken(signedToken string) (*SignedDetails, error) {
 	claims := &SignedDetails{}
-	token, err := jwt.ParseWithClaims(signedToken, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(SECRET_KEY), nil
-	})
+	_, err := jwt.ParseWithClaims(signedToken, claims, Keys.Keyfunc, ParserOptions()...)
 	if err != nil {
 		return nil, err
 	}
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, err
-	}
-	if claims.ExpiresAt.Time.Before(time.Now()) {
-		return nil, errors.New("token expired")
+	if claims.TokenType != AccessTokenType {
+		return nil, errors.New("not an access token")
 	}
 	return claims, nil
 }
 
 func ValidateRefreshToken(signedToken string) (*SignedDetails, error) {
 	claims := &SignedDetails{}
-	token, err := jwt.ParseWithClaims(signedToken, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(REFRESH_SECRET_KEY), nil
-	})
+	_, err := jwt.ParseWithClaims(signedToken, claims, Keys.Keyfunc, ParserOptions()...)
 	if err != nil {
 		return nil, err
 	}
-	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-		return nil, err
-	}
-	if claims.ExpiresAt.Time.Before(time.Now()) {
-		return nil, errors.New("refresh token expired")
+	if claims.TokenType != RefreshTokenType {
+		return nil, errors.New("not a refresh token")
 	}
 	return claims, nil
 }
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt/v5"
+)
+
+// TOTP parameters (RFC 6238) understood by every common authenticator app.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// totpSkew is how many steps either side of now are accepted, to allow
+	// for clock drift between the server and the phone.
+	totpSkew = 1
+)
+
+// MFAChallengeTTL is how long the second factor may be entered after the
+// password step.
+const MFAChallengeTTL = 5 * time.Minute
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base32NoPadding.EncodeToString(buf), nil
+}
+
+// TOTPURI returns the otpauth:// URI authenticator apps import, usually via a QR code.
+func TOTPURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(totpDigits))
+	v.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1_000_000)
+}
+
+// ValidateTOTP checks code against secret and returns the time step it
+// matched. Steps at or before lastStep are rejected so a code cannot be replayed.
+func ValidateTOTP(secret, code string, lastStep int64) (int64, bool) {
+	key, err := base32NoPadding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.ReplaceAll(code, " ", "")
+	now := time.Now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns n single-use codes and the hashes to store.
+func GenerateRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, n)
+	hashes := make([]string, n)
+	for i := range codes {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(base32NoPadding.EncodeToString(buf))
+		codes[i] = raw[:4] + "-" + raw[4:]
+		hashes[i] = HashRecoveryCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode normalises a code as typed by the user before hashing it.
+func HashRecoveryCode(code string) string {
+	return HashOpaqueToken(strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", "")))
+}
+
+type MFAChallenge struct {
+	UserID string
+	jwt.RegisteredClaims
+}
+
+// GenerateMFAChallenge issues the short-lived token that proves the password
+// step succeeded while the second factor is still outstanding.
+func GenerateMFAChallenge(userId string) (string, error) {
+	return Keys.Sign(&MFAChallenge{
+		UserID: userId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "MagicStreamMovies",
+			Subject:   "mfa-challenge",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(MFAChallengeTTL)),
+		},
+	})
+}
+
+func ValidateMFAChallenge(signedToken string) (*MFAChallenge, error) {
+	claims := &MFAChallenge{}
+	_, err := jwt.ParseWithClaims(signedToken, claims, Keys.Keyfunc, append(ParserOptions(), jwt.WithSubject("mfa-challenge"))...)
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// RequireAdminMFA reports whether admins must use two-factor authentication.
+func RequireAdminMFA() bool {
+	return envBool("REQUIRE_ADMIN_MFA", false)
+}